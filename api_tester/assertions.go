@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// AssertionResult records the outcome of one suite assertion
+type AssertionResult struct {
+	Expression string `json:"expression"`
+	Passed     bool   `json:"passed"`
+	Message    string `json:"message"`
+}
+
+// evaluateAssertions runs every assertion against the raw response body
+func evaluateAssertions(assertions []string, body []byte) []AssertionResult {
+	results := make([]AssertionResult, 0, len(assertions))
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		for _, expr := range assertions {
+			results = append(results, AssertionResult{
+				Expression: expr,
+				Message:    fmt.Sprintf("response body is not valid JSON: %v", err),
+			})
+		}
+		return results
+	}
+
+	for _, expr := range assertions {
+		passed, message, err := evaluateAssertion(expr, doc)
+		if err != nil {
+			message = err.Error()
+		}
+		results = append(results, AssertionResult{
+			Expression: expr,
+			Passed:     passed && err == nil,
+			Message:    message,
+		})
+	}
+
+	return results
+}
+
+// evaluateAssertion evaluates a single expression of one of the forms
+//
+//	<path>                  the selection is non-empty
+//	<path> <op> <literal>   every matched node satisfies the comparison
+//	length(<path>) <op> <n> the length of the match satisfies the comparison
+func evaluateAssertion(expr string, doc interface{}) (bool, string, error) {
+	left, op, right, found := splitComparison(expr)
+	if !found {
+		length, err := jsonPathMatchLength(expr, doc)
+		if err != nil {
+			return false, "", err
+		}
+		if length == 0 {
+			return false, "no values matched", nil
+		}
+		return true, fmt.Sprintf("%d value(s) matched", length), nil
+	}
+
+	expected, err := parseJSONLiteral(right)
+	if err != nil {
+		return false, "", err
+	}
+
+	// length(...) compares the size of the selection rather than its values
+	if strings.HasPrefix(left, "length(") && strings.HasSuffix(left, ")") {
+		length, err := jsonPathMatchLength(left[len("length("):len(left)-1], doc)
+		if err != nil {
+			return false, "", err
+		}
+		ok, err := compareJSONValues(float64(length), op, expected)
+		if err != nil {
+			return false, "", err
+		}
+		return ok, fmt.Sprintf("length is %d", length), nil
+	}
+
+	nodes, err := evaluateJSONPath(left, doc)
+	if err != nil {
+		return false, "", err
+	}
+	if len(nodes) == 0 {
+		return false, "no values matched", nil
+	}
+
+	var failures []string
+	for _, node := range nodes {
+		ok, err := compareJSONValues(node.Value, op, expected)
+		if err != nil {
+			return false, "", err
+		}
+		if !ok {
+			failures = append(failures, fmt.Sprintf("%s = %v", node.Path, formatJSONValue(node.Value)))
+		}
+	}
+	if len(failures) > 0 {
+		// Keep the message readable for large responses
+		shown := failures
+		if len(shown) > 5 {
+			shown = shown[:5]
+		}
+		message := fmt.Sprintf("%d of %d value(s) failed: %s", len(failures), len(nodes), strings.Join(shown, ", "))
+		if len(failures) > len(shown) {
+			message += ", ..."
+		}
+		return false, message, nil
+	}
+
+	return true, fmt.Sprintf("all %d value(s) passed", len(nodes)), nil
+}
+
+// jsonPathMatchLength counts the matches of a path that can select several nodes,
+// or the size of the array, object or string a singular path points at
+func jsonPathMatchLength(expr string, doc interface{}) (int, error) {
+	segments, err := parseJSONPath(expr)
+	if err != nil {
+		return 0, err
+	}
+	nodes, err := evaluateJSONPath(expr, doc)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, segment := range segments {
+		if segment.kind == "wildcard" || segment.kind == "filter" || segment.kind == "descendant" {
+			return len(nodes), nil
+		}
+	}
+	if len(nodes) == 0 {
+		return 0, nil
+	}
+
+	switch value := nodes[0].Value.(type) {
+	case []interface{}:
+		return len(value), nil
+	case map[string]interface{}:
+		return len(value), nil
+	case string:
+		return len(value), nil
+	}
+	return 1, nil
+}
+
+// formatJSONValue renders a decoded value the way it appeared in the response
+func formatJSONValue(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+// printAssertionResults displays assertion outcomes one per line
+func printAssertionResults(results []AssertionResult) {
+	for _, result := range results {
+		if result.Passed {
+			fmt.Printf("✅ %s (%s)\n", result.Expression, result.Message)
+		} else {
+			fmt.Printf("❌ %s (%s)\n", result.Expression, result.Message)
+		}
+	}
+}
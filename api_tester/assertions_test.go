@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+)
+
+const assertionTestBody = `[
+  {"id": 1, "title": "Backpack", "price": 109.95, "category": "men's clothing", "rating": {"rate": 3.9, "count": 120}},
+  {"id": 2, "title": "SSD", "price": 64, "category": "electronics", "rating": {"rate": 4.8, "count": 400}},
+  {"id": 3, "title": "Monitor", "price": 0, "category": "electronics", "rating": {"rate": 2.9, "count": 250}}
+]`
+
+func TestEvaluateAssertions(t *testing.T) {
+	testCases := []struct {
+		expression string
+		expected   bool
+	}{
+		{"$[*].price >= 0", true},
+		{"$[*].price > 0", false},
+		{"length($) == 3", true},
+		{"length($) == 20", false},
+		{"$[?(@.category=='electronics')]", true},
+		{"$[?(@.category=='jewelery')]", false},
+		{"length($[?(@.category == 'electronics' && @.price > 10)]) == 1", true},
+		{"$[?(@.rating.rate > 4.5 || @.price == 0)].id > 1", true},
+		{"$[0].title == 'Backpack'", true},
+		{"$[-1].id == 3", true},
+		{"$..count <= 400", true},
+		{"$[*].discount > 0", false},
+		{"$[*].price >", false},
+		{"products[0]", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			results := evaluateAssertions([]string{tc.expression}, []byte(assertionTestBody))
+			if len(results) != 1 {
+				t.Fatalf("Expected 1 result, got %d", len(results))
+			}
+			if results[0].Passed != tc.expected {
+				t.Errorf("Expected passed=%v, got %v (%s)", tc.expected, results[0].Passed, results[0].Message)
+			}
+		})
+	}
+}
+
+func TestEvaluateAssertionsInvalidJSON(t *testing.T) {
+	results := evaluateAssertions([]string{"$[*].price > 0"}, []byte(`not json`))
+	if len(results) != 1 || results[0].Passed {
+		t.Errorf("Expected a failed assertion for invalid JSON, got %+v", results)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// GraphQLQuery describes a GraphQL request whose response contains products
+type GraphQLQuery struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	OperationName string                 `json:"operation_name,omitempty"`
+	// DataPath is a JSONPath into the "data" object selecting the products
+	DataPath string `json:"data_path"`
+}
+
+// GraphQLError is an entry of the "errors" array of a GraphQL response
+type GraphQLError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// graphQLResponse is the envelope every GraphQL server responds with
+type graphQLResponse struct {
+	Data   interface{}    `json:"data"`
+	Errors []GraphQLError `json:"errors"`
+}
+
+// fetchGraphQLResponse posts a GraphQL query and returns the raw response body
+func fetchGraphQLResponse(url string, q *GraphQLQuery) (*http.Response, []byte, error) {
+	payload := map[string]interface{}{
+		"query": q.Query,
+	}
+	if len(q.Variables) > 0 {
+		payload["variables"] = q.Variables
+	}
+	if q.OperationName != "" {
+		payload["operationName"] = q.OperationName
+	}
+
+	requestBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode GraphQL request: %w", err)
+	}
+
+	// Make HTTP request
+	resp, err := httpClient.Post(url, "application/json", bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return resp, body, nil
+}
+
+// parseGraphQLProducts decodes a GraphQL response and converts the selected data into products
+func parseGraphQLProducts(body []byte, dataPath string) ([]Product, []GraphQLError, error) {
+	var envelope graphQLResponse
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	// A response with errors and no data is still a valid GraphQL answer
+	if envelope.Data == nil {
+		if len(envelope.Errors) == 0 {
+			return nil, nil, fmt.Errorf("GraphQL response has neither data nor errors")
+		}
+		return nil, envelope.Errors, nil
+	}
+
+	if dataPath == "" {
+		dataPath = "$"
+	}
+	nodes, err := evaluateJSONPath(dataPath, envelope.Data)
+	if err != nil {
+		return nil, envelope.Errors, err
+	}
+
+	// A single array match is the product list itself, otherwise each match is a product
+	var selected []interface{}
+	if len(nodes) == 1 {
+		if arr, ok := nodes[0].Value.([]interface{}); ok {
+			selected = arr
+		}
+	}
+	if selected == nil {
+		for _, node := range nodes {
+			selected = append(selected, node.Value)
+		}
+	}
+
+	raw, err := json.Marshal(selected)
+	if err != nil {
+		return nil, envelope.Errors, fmt.Errorf("failed to re-encode GraphQL data: %w", err)
+	}
+	var products []Product
+	if err := json.Unmarshal(raw, &products); err != nil {
+		return nil, envelope.Errors, fmt.Errorf("data at %s is not a list of products: %w", dataPath, err)
+	}
+
+	return products, envelope.Errors, nil
+}
+
+// printGraphQLErrors lists the errors returned by a GraphQL server
+func printGraphQLErrors(errors []GraphQLError) {
+	for _, gqlErr := range errors {
+		if len(gqlErr.Path) > 0 {
+			fmt.Printf("- %s (path: %v)\n", gqlErr.Message, gqlErr.Path)
+		} else {
+			fmt.Printf("- %s\n", gqlErr.Message)
+		}
+	}
+}
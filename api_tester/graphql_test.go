@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchGraphQLProducts(t *testing.T) {
+	testCases := []struct {
+		name           string
+		responseBody   string
+		dataPath       string
+		expectedCount  int
+		expectedErrors int
+		expectedError  bool
+	}{
+		{
+			name:          "Products list",
+			responseBody:  `{"data":{"products":[{"id":1,"title":"A","price":1},{"id":2,"title":"B","price":2}]}}`,
+			dataPath:      "$.products",
+			expectedCount: 2,
+		},
+		{
+			name:          "Nested wildcard selection",
+			responseBody:  `{"data":{"categories":[{"items":[{"id":1}]},{"items":[{"id":2},{"id":3}]}]}}`,
+			dataPath:      "$.categories[*].items[*]",
+			expectedCount: 3,
+		},
+		{
+			name:           "Errors with partial data",
+			responseBody:   `{"data":{"products":[{"id":1,"title":"A"}]},"errors":[{"message":"price unavailable","path":["products",0,"price"]}]}`,
+			dataPath:       "$.products",
+			expectedCount:  1,
+			expectedErrors: 1,
+		},
+		{
+			name:           "Errors without data",
+			responseBody:   `{"data":null,"errors":[{"message":"Cannot query field \"prodcts\""}]}`,
+			dataPath:       "$.products",
+			expectedErrors: 1,
+		},
+		{
+			name:          "Data is not a product list",
+			responseBody:  `{"data":{"products":"nope"}}`,
+			dataPath:      "$.products",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var received map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&received)
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(tc.responseBody))
+			}))
+			defer server.Close()
+
+			query := &GraphQLQuery{
+				Query:     "query($limit: Int) { products(limit: $limit) { id title price } }",
+				Variables: map[string]interface{}{"limit": 5},
+				DataPath:  tc.dataPath,
+			}
+			resp, body, err := fetchGraphQLResponse(server.URL, query)
+			if err != nil {
+				t.Fatalf("Expected the request to succeed, got %v", err)
+			}
+			products, gqlErrors, err := parseGraphQLProducts(body, query.DataPath)
+
+			if tc.expectedError && err == nil {
+				t.Errorf("Expected error, got nil")
+			}
+			if !tc.expectedError && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("Expected status code 200, got %d", resp.StatusCode)
+			}
+			if len(products) != tc.expectedCount {
+				t.Errorf("Expected %d products, got %d", tc.expectedCount, len(products))
+			}
+			if len(gqlErrors) != tc.expectedErrors {
+				t.Errorf("Expected %d GraphQL errors, got %d", tc.expectedErrors, len(gqlErrors))
+			}
+
+			// The query and variables must be sent in the standard envelope
+			if received["query"] != query.Query {
+				t.Errorf("Expected query to be posted, got %v", received["query"])
+			}
+			if vars, ok := received["variables"].(map[string]interface{}); !ok || vars["limit"] != float64(5) {
+				t.Errorf("Expected variables to be posted, got %v", received["variables"])
+			}
+		})
+	}
+}
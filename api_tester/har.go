@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// redactedHeaders are replaced before traffic is written to a HAR file
+var redactedHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"set-cookie":          true,
+	"x-api-key":           true,
+	"x-auth-token":        true,
+}
+
+// redactedQueryParams are query parameters whose values are replaced in recorded URLs
+var redactedQueryParams = map[string]bool{
+	"access_token":  true,
+	"api_key":       true,
+	"apikey":        true,
+	"auth":          true,
+	"client_secret": true,
+	"key":           true,
+	"password":      true,
+	"secret":        true,
+	"signature":     true,
+	"token":         true,
+}
+
+// HAR is the root of a HAR 1.2 document
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog holds the recorded entries
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator identifies the application that produced the file
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry is one request/response exchange
+type HAREntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	// Error is a custom field set when no response was received
+	Error string `json:"_error,omitempty"`
+}
+
+// HARRequest describes the request sent
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARResponse describes the response received
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARNameValue is a header, cookie or query parameter
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData is the body of a request
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// HARContent is the body of a response
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// HARTimings breaks down the time spent on an entry; only the total wait is measured
+type HARTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// harRecorder is an http.RoundTripper that records every exchange passing through it
+type harRecorder struct {
+	base    http.RoundTripper
+	mu      sync.Mutex
+	entries []HAREntry
+}
+
+// newHARRecorder wraps base, falling back to the default transport
+func newHARRecorder(base http.RoundTripper) *harRecorder {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &harRecorder{base: base}
+}
+
+// RoundTrip sends the request through the wrapped transport and records the exchange
+func (h *harRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	started := time.Now()
+
+	// Read the request body so it can be recorded and still be sent
+	var requestBody []byte
+	if req.Body != nil {
+		var err error
+		requestBody, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(requestBody))
+	}
+
+	entry := HAREntry{
+		StartedDateTime: started.Format(time.RFC3339Nano),
+		Request:         harRequest(req, requestBody),
+	}
+
+	resp, err := h.base.RoundTrip(req)
+	if err != nil {
+		entry.Error = err.Error()
+		entry.Response = HARResponse{Cookies: []HARNameValue{}, Headers: []HARNameValue{}, HTTPVersion: req.Proto, BodySize: -1, HeadersSize: -1}
+		h.finish(entry, started)
+		return nil, err
+	}
+
+	// Buffer the response body so the caller can still read it. A failed read is recorded,
+	// and the caller gets the bytes read so far followed by the same error
+	responseBody, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	var bodyReader io.Reader = bytes.NewReader(responseBody)
+	if readErr != nil {
+		entry.Error = readErr.Error()
+		bodyReader = io.MultiReader(bodyReader, errorReader{readErr})
+	}
+	resp.Body = io.NopCloser(bodyReader)
+
+	entry.Response = harResponse(resp, responseBody)
+	h.finish(entry, started)
+	return resp, nil
+}
+
+// errorReader fails every read with its error
+type errorReader struct {
+	err error
+}
+
+// Read returns the reader's error
+func (r errorReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// finish stores an entry with its elapsed time
+func (h *harRecorder) finish(entry HAREntry, started time.Time) {
+	elapsed := float64(time.Since(started).Microseconds()) / 1000
+	entry.Time = elapsed
+	entry.Timings = HARTimings{Send: 0, Wait: elapsed, Receive: 0}
+
+	h.mu.Lock()
+	h.entries = append(h.entries, entry)
+	h.mu.Unlock()
+}
+
+// harRequest converts a request into its HAR form
+func harRequest(req *http.Request, body []byte) HARRequest {
+	request := HARRequest{
+		Method:      req.Method,
+		URL:         redactURL(req.URL),
+		HTTPVersion: req.Proto,
+		Cookies:     []HARNameValue{},
+		Headers:     harHeaders(req.Header),
+		QueryString: []HARNameValue{},
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	query := req.URL.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range query[name] {
+			if redactedQueryParams[strings.ToLower(name)] {
+				value = "[REDACTED]"
+			}
+			request.QueryString = append(request.QueryString, HARNameValue{Name: name, Value: value})
+		}
+	}
+	if len(body) > 0 {
+		request.PostData = &HARPostData{MimeType: req.Header.Get("Content-Type"), Text: string(body)}
+	}
+	return request
+}
+
+// harResponse converts a response into its HAR form
+func harResponse(resp *http.Response, body []byte) HARResponse {
+	content := HARContent{
+		Size:     len(body),
+		MimeType: resp.Header.Get("Content-Type"),
+	}
+	if utf8.Valid(body) {
+		content.Text = string(body)
+	} else {
+		content.Text = base64.StdEncoding.EncodeToString(body)
+		content.Encoding = "base64"
+	}
+
+	return HARResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Cookies:     []HARNameValue{},
+		Headers:     harHeaders(resp.Header),
+		Content:     content,
+		RedirectURL: redactLocation(resp.Header.Get("Location")),
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+}
+
+// redactURL renders a URL with the password and sensitive query parameter values redacted
+func redactURL(u *url.URL) string {
+	redacted := *u
+	query := u.Query()
+	changed := false
+	for name, values := range query {
+		if redactedQueryParams[strings.ToLower(name)] {
+			for i := range values {
+				values[i] = "[REDACTED]"
+			}
+			changed = true
+		}
+	}
+	if changed {
+		redacted.RawQuery = query.Encode()
+	}
+	return redacted.Redacted()
+}
+
+// redactLocation redacts a Location header value, which may be empty or relative
+func redactLocation(location string) string {
+	u, err := url.Parse(location)
+	if location == "" || err != nil {
+		return location
+	}
+	return redactURL(u)
+}
+
+// harHeaders lists headers in a stable order with sensitive values redacted, including
+// sensitive query parameters in URL-valued headers
+func harHeaders(header http.Header) []HARNameValue {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	headers := []HARNameValue{}
+	for _, name := range names {
+		for _, value := range header[name] {
+			switch lower := strings.ToLower(name); {
+			case redactedHeaders[lower]:
+				value = "[REDACTED]"
+			case lower == "location" || lower == "content-location" || lower == "referer":
+				value = redactLocation(value)
+			}
+			headers = append(headers, HARNameValue{Name: name, Value: value})
+		}
+	}
+	return headers
+}
+
+// writeHAR saves the recorded traffic as a HAR 1.2 file
+func (h *harRecorder) writeHAR(filename string) error {
+	h.mu.Lock()
+	entries := append([]HAREntry{}, h.entries...)
+	h.mu.Unlock()
+
+	har := HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "api_tester", Version: "1.0"},
+		Entries: entries,
+	}}
+
+	data, err := json.MarshalIndent(har, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode HAR: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write HAR file %s: %w", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHARRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret-session"})
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/products/1?token=secret-location")
+		w.Write([]byte(`[{"id":1}]`))
+	}))
+	defer server.Close()
+
+	recorder := newHARRecorder(nil)
+	client := &http.Client{Transport: recorder}
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/products?limit=5&api_key=secret-key&Token=secret-query-token", strings.NewReader(`{"title":"x"}`))
+	req.Header.Set("Authorization", "Bearer secret-token")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	// Failed requests are recorded too
+	if _, err := client.Get("http://127.0.0.1:1/unreachable"); err == nil {
+		t.Fatalf("Expected request to unreachable host to fail")
+	}
+
+	tempDir, err := os.MkdirTemp("", "har-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	filename := filepath.Join(tempDir, "traffic.har")
+	if err := recorder.writeHAR(filename); err != nil {
+		t.Fatalf("Failed to write HAR: %v", err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read HAR: %v", err)
+	}
+
+	var har HAR
+	if err := json.Unmarshal(data, &har); err != nil {
+		t.Fatalf("HAR file is not valid JSON: %v", err)
+	}
+	if har.Log.Version != "1.2" || len(har.Log.Entries) != 2 {
+		t.Fatalf("Expected HAR 1.2 with 2 entries, got version %s with %d entries", har.Log.Version, len(har.Log.Entries))
+	}
+
+	entry := har.Log.Entries[0]
+	if entry.Request.Method != http.MethodPost || entry.Request.PostData == nil || entry.Request.PostData.Text != `{"title":"x"}` {
+		t.Errorf("Unexpected request %+v", entry.Request)
+	}
+	expectedQuery := []HARNameValue{{Name: "Token", Value: "[REDACTED]"}, {Name: "api_key", Value: "[REDACTED]"}, {Name: "limit", Value: "5"}}
+	if !reflect.DeepEqual(entry.Request.QueryString, expectedQuery) {
+		t.Errorf("Expected query string %+v, got %+v", expectedQuery, entry.Request.QueryString)
+	}
+	if !strings.Contains(entry.Request.URL, "api_key=%5BREDACTED%5D") || !strings.Contains(entry.Request.URL, "limit=5") {
+		t.Errorf("Expected the URL to keep the query with redacted values, got %s", entry.Request.URL)
+	}
+	if entry.Response.Status != http.StatusOK || entry.Response.Content.Text != `[{"id":1}]` {
+		t.Errorf("Unexpected response %+v", entry.Response)
+	}
+	if har.Log.Entries[1].Error == "" {
+		t.Errorf("Expected the failed request to carry an error")
+	}
+
+	// Sensitive values must never reach the file
+	if strings.Contains(string(data), "secret-") {
+		t.Errorf("HAR file contains unredacted secrets")
+	}
+}
+
+// failingTransport returns a response whose body fails after its first bytes
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := io.MultiReader(strings.NewReader(`[{"id"`), errorReader{errors.New("connection reset")})
+	return &http.Response{StatusCode: http.StatusOK, Proto: "HTTP/1.1", Header: http.Header{}, Body: io.NopCloser(body), Request: req}, nil
+}
+
+func TestHARRecorderBodyError(t *testing.T) {
+	recorder := newHARRecorder(failingTransport{})
+	client := &http.Client{Transport: recorder}
+
+	resp, err := client.Get("http://example.test/products")
+	if err != nil {
+		t.Fatalf("Expected the response to be returned, got %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if string(body) != `[{"id"` || err == nil || err.Error() != "connection reset" {
+		t.Errorf("Expected the partial body and the read error, got %q (%v)", body, err)
+	}
+
+	if len(recorder.entries) != 1 || recorder.entries[0].Error != "connection reset" {
+		t.Errorf("Expected the failed read to be recorded, got %+v", recorder.entries)
+	}
+}
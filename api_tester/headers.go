@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// HeaderChecks describes the response header and caching behaviour a suite expects
+type HeaderChecks struct {
+	// Required lists headers that must be present
+	Required []string `json:"required,omitempty"`
+	// Expected maps header names to a value the header must contain (case-insensitive)
+	Expected map[string]string `json:"expected,omitempty"`
+	CORS     *CORSCheck        `json:"cors,omitempty"`
+	// Conditional re-requests the resource with If-None-Match and expects 304 Not Modified
+	Conditional bool `json:"conditional,omitempty"`
+	// Compression lists content codings (e.g. gzip, br) the server must apply when accepted
+	Compression []string `json:"compression,omitempty"`
+}
+
+// CORSCheck describes the cross-origin requests the API must allow
+type CORSCheck struct {
+	Origin string `json:"origin"`
+	// Methods, when set, are checked with a preflight OPTIONS request
+	Methods []string `json:"methods,omitempty"`
+}
+
+// HeaderCheckResult is the outcome of a single header check
+type HeaderCheckResult struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message"`
+}
+
+// runHeaderChecks checks the headers of the main response and sends the extra requests
+// needed for CORS, conditional and compression checks
+func runHeaderChecks(checks *HeaderChecks, url string, header http.Header) []HeaderCheckResult {
+	var results []HeaderCheckResult
+
+	for _, name := range checks.Required {
+		result := HeaderCheckResult{Name: "Required header " + name}
+		if value := header.Get(name); value != "" {
+			result.Passed = true
+			result.Message = fmt.Sprintf("%s: %s", name, value)
+		} else {
+			result.Message = fmt.Sprintf("Header %s is missing", name)
+		}
+		results = append(results, result)
+	}
+
+	names := make([]string, 0, len(checks.Expected))
+	for name := range checks.Expected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		expected := checks.Expected[name]
+		result := HeaderCheckResult{Name: "Header value " + name}
+		actual := header.Get(name)
+		switch {
+		case actual == "":
+			result.Message = fmt.Sprintf("Header %s is missing, expected %q", name, expected)
+		case strings.Contains(strings.ToLower(actual), strings.ToLower(expected)):
+			result.Passed = true
+			result.Message = fmt.Sprintf("%s: %s", name, actual)
+		default:
+			result.Message = fmt.Sprintf("Expected %s to contain %q, got %q", name, expected, actual)
+		}
+		results = append(results, result)
+	}
+
+	if checks.CORS != nil {
+		results = append(results, checkCORS(url, checks.CORS)...)
+	}
+	if checks.Conditional {
+		results = append(results, checkConditionalRequest(url, header.Get("ETag")))
+	}
+	for _, encoding := range checks.Compression {
+		results = append(results, checkCompression(url, encoding))
+	}
+
+	return results
+}
+
+// sendWithHeaders performs a request with extra headers and returns the response with its body
+func sendWithHeaders(method, url string, headers map[string]string) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return resp, body, nil
+}
+
+// checkCORS verifies the allowed origin and, if configured, the preflight response
+func checkCORS(url string, cors *CORSCheck) []HeaderCheckResult {
+	result := HeaderCheckResult{Name: "CORS origin " + cors.Origin}
+	resp, _, err := sendWithHeaders(http.MethodGet, url, map[string]string{"Origin": cors.Origin})
+	if err != nil {
+		result.Message = err.Error()
+	} else if allowed := resp.Header.Get("Access-Control-Allow-Origin"); allowed == cors.Origin || allowed == "*" {
+		result.Passed = true
+		result.Message = "Access-Control-Allow-Origin: " + allowed
+	} else if allowed == "" {
+		result.Message = "Access-Control-Allow-Origin is missing"
+	} else {
+		result.Message = fmt.Sprintf("Access-Control-Allow-Origin is %q, expected %q or \"*\"", allowed, cors.Origin)
+	}
+	results := []HeaderCheckResult{result}
+
+	if len(cors.Methods) == 0 {
+		return results
+	}
+
+	preflight := HeaderCheckResult{Name: "CORS preflight " + strings.Join(cors.Methods, ", ")}
+	resp, _, err = sendWithHeaders(http.MethodOptions, url, map[string]string{
+		"Origin":                        cors.Origin,
+		"Access-Control-Request-Method": cors.Methods[0],
+	})
+	if err != nil {
+		preflight.Message = err.Error()
+		return append(results, preflight)
+	}
+
+	allowedMethods := map[string]bool{}
+	for _, method := range splitList(resp.Header.Get("Access-Control-Allow-Methods")) {
+		allowedMethods[strings.ToUpper(method)] = true
+	}
+	var missing []string
+	for _, method := range cors.Methods {
+		if !allowedMethods[strings.ToUpper(method)] && !allowedMethods["*"] {
+			missing = append(missing, method)
+		}
+	}
+	if len(missing) == 0 {
+		preflight.Passed = true
+		preflight.Message = "Access-Control-Allow-Methods: " + resp.Header.Get("Access-Control-Allow-Methods")
+	} else {
+		preflight.Message = fmt.Sprintf("Preflight (status %d) does not allow %s", resp.StatusCode, strings.Join(missing, ", "))
+	}
+	return append(results, preflight)
+}
+
+// checkConditionalRequest verifies that revalidating with the ETag yields 304 Not Modified
+func checkConditionalRequest(url, etag string) HeaderCheckResult {
+	result := HeaderCheckResult{Name: "Conditional request (If-None-Match)"}
+	if etag == "" {
+		result.Message = "Response has no ETag header"
+		return result
+	}
+
+	resp, _, err := sendWithHeaders(http.MethodGet, url, map[string]string{"If-None-Match": etag})
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		result.Passed = true
+		result.Message = fmt.Sprintf("ETag %s yields 304 Not Modified", etag)
+	} else {
+		result.Message = fmt.Sprintf("Expected 304 for If-None-Match %s, got %d", etag, resp.StatusCode)
+	}
+	return result
+}
+
+// checkCompression verifies that the server encodes the body when the coding is accepted.
+// Setting Accept-Encoding explicitly stops the transport from decompressing transparently
+func checkCompression(url, encoding string) HeaderCheckResult {
+	result := HeaderCheckResult{Name: "Compression " + encoding}
+	resp, body, err := sendWithHeaders(http.MethodGet, url, map[string]string{"Accept-Encoding": encoding})
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+
+	actual := resp.Header.Get("Content-Encoding")
+	if !strings.EqualFold(actual, encoding) {
+		if actual == "" {
+			actual = "none"
+		}
+		result.Message = fmt.Sprintf("Expected Content-Encoding %s, got %s", encoding, actual)
+		return result
+	}
+
+	// gzip can be verified with the standard library; other codings are trusted by header
+	if strings.EqualFold(encoding, "gzip") {
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		if err == nil {
+			_, err = io.ReadAll(reader)
+		}
+		if err != nil {
+			result.Message = fmt.Sprintf("Body is not valid gzip: %v", err)
+			return result
+		}
+	}
+
+	result.Passed = true
+	result.Message = fmt.Sprintf("Content-Encoding: %s (%d bytes)", actual, len(body))
+	return result
+}
+
+// printHeaderCheck displays the outcome of one header check
+func printHeaderCheck(result HeaderCheckResult) {
+	if result.Passed {
+		fmt.Printf("✅ %s\n", result.Message)
+	} else {
+		fmt.Printf("❌ %s\n", result.Message)
+	}
+}
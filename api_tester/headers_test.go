@@ -0,0 +1,108 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// setupHeaderServer returns a server that supports caching, CORS and gzip when wellBehaved is true
+func setupHeaderServer(wellBehaved bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if !wellBehaved {
+			w.Write([]byte(`[]`))
+			return
+		}
+
+		w.Header().Set("Cache-Control", "public, max-age=60")
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if r.Header.Get("Accept-Encoding") == "gzip" {
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			gz.Write([]byte(`[]`))
+			gz.Close()
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+}
+
+func TestRunHeaderChecks(t *testing.T) {
+	checks := &HeaderChecks{
+		Required:    []string{"Content-Type", "Cache-Control"},
+		Expected:    map[string]string{"Content-Type": "application/json"},
+		CORS:        &CORSCheck{Origin: "https://app.example.com", Methods: []string{"GET", "POST"}},
+		Conditional: true,
+		Compression: []string{"gzip"},
+	}
+
+	testCases := []struct {
+		name        string
+		wellBehaved bool
+		expected    map[string]bool
+	}{
+		{
+			name:        "Well-behaved server",
+			wellBehaved: true,
+			expected: map[string]bool{
+				"Required header Content-Type":        true,
+				"Required header Cache-Control":       true,
+				"Header value Content-Type":           true,
+				"CORS origin https://app.example.com": true,
+				"CORS preflight GET, POST":            true,
+				"Conditional request (If-None-Match)": true,
+				"Compression gzip":                    true,
+			},
+		},
+		{
+			name:        "Server without caching, CORS or compression",
+			wellBehaved: false,
+			expected: map[string]bool{
+				"Required header Content-Type":        true,
+				"Required header Cache-Control":       false,
+				"Header value Content-Type":           true,
+				"CORS origin https://app.example.com": false,
+				"CORS preflight GET, POST":            false,
+				"Conditional request (If-None-Match)": false,
+				"Compression gzip":                    false,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := setupHeaderServer(tc.wellBehaved)
+			defer server.Close()
+
+			report, _, err := runTests(server.URL, runOptions{Suite: &Suite{Headers: checks}})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(report.HeaderChecks) != len(tc.expected) {
+				t.Fatalf("Expected %d header checks, got %d: %+v", len(tc.expected), len(report.HeaderChecks), report.HeaderChecks)
+			}
+			for _, result := range report.HeaderChecks {
+				expected, ok := tc.expected[result.Name]
+				if !ok {
+					t.Errorf("Unexpected check %q", result.Name)
+					continue
+				}
+				if result.Passed != expected {
+					t.Errorf("Expected %q passed=%v, got %v (%s)", result.Name, expected, result.Passed, result.Message)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// severityWeights is how much a single defect of each severity lowers a product's score
+var severityWeights = map[string]float64{
+	SeverityCritical: 1.0,
+	SeverityMajor:    0.5,
+	SeverityMinor:    0.2,
+}
+
+// qualityScore is the percentage of clean products, where each product loses the weight
+// of its defects (a product can't score below zero)
+func qualityScore(totalProducts int, defects []ValidationError) float64 {
+	if totalProducts == 0 {
+		return 100
+	}
+
+	penalties := map[int]float64{}
+	for _, defect := range defects {
+		weight, ok := severityWeights[defect.Severity]
+		if !ok {
+			weight = severityWeights[SeverityMajor]
+		}
+		penalties[defect.ProductID] += weight
+	}
+
+	lost := 0.0
+	for _, penalty := range penalties {
+		if penalty > 1 {
+			penalty = 1
+		}
+		lost += penalty
+	}
+
+	score := 100 * (1 - lost/float64(totalProducts))
+	if score < 0 {
+		score = 0
+	}
+	// Round to two decimals so history files stay readable
+	return float64(int64(score*100+0.5)) / 100
+}
+
+// appendHistory adds a report as one line to a JSONL history file
+func appendHistory(filename string, report TestReport) error {
+	line, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	return nil
+}
+
+// loadHistory reads the last n reports from a history file, optionally only those for one URL
+func loadHistory(filename string, n int, urlFilter string) ([]TestReport, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer f.Close()
+
+	var reports []TestReport
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var report TestReport
+		if err := json.Unmarshal([]byte(line), &report); err != nil {
+			return nil, fmt.Errorf("history line %d is not a valid report: %w", lineNumber, err)
+		}
+		if urlFilter != "" && report.URL != urlFilter {
+			continue
+		}
+		reports = append(reports, report)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	if n > 0 && len(reports) > n {
+		reports = reports[len(reports)-n:]
+	}
+	return reports, nil
+}
+
+// runHistoryCommand implements the "history" subcommand
+func runHistoryCommand(args []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	historyFile := fs.String("file", "history.jsonl", "History file written by -history")
+	lastRuns := fs.Int("n", 10, "Number of most recent runs to show")
+	urlFilter := fs.String("url", "", "Only show runs against this URL")
+	fs.Parse(args)
+
+	reports, err := loadHistory(*historyFile, *lastRuns, *urlFilter)
+	if err != nil {
+		fmt.Printf("Error loading history: %v\n", err)
+		os.Exit(1)
+	}
+	if len(reports) == 0 {
+		fmt.Println("No runs recorded yet")
+		return
+	}
+
+	fmt.Println("API Tester - Data-Quality History")
+	fmt.Println("=================================")
+	fmt.Println()
+	printHistory(os.Stdout, reports)
+}
+
+// printHistory displays the score of each run followed by defect counts per field across runs
+func printHistory(out io.Writer, reports []TestReport) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Run\tTimestamp\tURL\tProducts\tDefects\tScore")
+	fmt.Fprintln(w, "---\t---------\t---\t--------\t-------\t-----")
+	for i, report := range reports {
+		fmt.Fprintf(w, "#%d\t%s\t%s\t%d\t%d\t%.2f\n",
+			i+1,
+			report.Timestamp,
+			report.URL,
+			report.TotalProducts,
+			report.DefectCount,
+			report.QualityScore,
+		)
+	}
+	w.Flush()
+	fmt.Fprintln(out)
+
+	// Count defects per field in every run
+	counts := make([]map[string]int, len(reports))
+	fieldSet := map[string]bool{}
+	for i, report := range reports {
+		counts[i] = map[string]int{}
+		for _, defect := range report.Defects {
+			counts[i][defect.Field]++
+			fieldSet[defect.Field] = true
+		}
+	}
+	if len(fieldSet) == 0 {
+		fmt.Fprintln(out, "✅ No defects recorded in these runs")
+		return
+	}
+	fields := make([]string, 0, len(fieldSet))
+	for field := range fieldSet {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	fmt.Fprintln(out, "Defects per field:")
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	header := []string{"Field"}
+	separator := []string{"-----"}
+	for i := range reports {
+		header = append(header, fmt.Sprintf("#%d", i+1))
+		separator = append(separator, "--")
+	}
+	fmt.Fprintln(w, strings.Join(append(header, "Trend"), "\t"))
+	fmt.Fprintln(w, strings.Join(append(separator, "-----"), "\t"))
+	for _, field := range fields {
+		row := []string{field}
+		for i := range reports {
+			row = append(row, fmt.Sprint(counts[i][field]))
+		}
+		first, last := counts[0][field], counts[len(counts)-1][field]
+		row = append(row, historyTrend(first, last))
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+}
+
+// historyTrend describes how a defect count moved between the first and last run
+func historyTrend(first, last int) string {
+	switch {
+	case last < first:
+		return fmt.Sprintf("improving (%+d)", last-first)
+	case last > first:
+		return fmt.Sprintf("worsening (%+d)", last-first)
+	}
+	return "stable"
+}
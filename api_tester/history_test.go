@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestQualityScore(t *testing.T) {
+	testCases := []struct {
+		name     string
+		total    int
+		defects  []ValidationError
+		expected float64
+	}{
+		{"No products", 0, nil, 100},
+		{"Clean products", 4, nil, 100},
+		{"One critical defect", 4, []ValidationError{{ProductID: 1, Severity: SeverityCritical}}, 75},
+		{"One minor defect", 5, []ValidationError{{ProductID: 1, Severity: SeverityMinor}}, 96},
+		{
+			name:  "Penalty per product is capped",
+			total: 2,
+			defects: []ValidationError{
+				{ProductID: 1, Severity: SeverityCritical},
+				{ProductID: 1, Severity: SeverityCritical},
+				{ProductID: 2, Severity: SeverityMajor},
+			},
+			expected: 25,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if score := qualityScore(tc.total, tc.defects); score != tc.expected {
+				t.Errorf("Expected score %.2f, got %.2f", tc.expected, score)
+			}
+		})
+	}
+}
+
+func TestHistoryRoundTrip(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "history-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	filename := filepath.Join(tempDir, "history.jsonl")
+
+	runs := []TestReport{
+		{URL: "http://a", TotalProducts: 2, DefectCount: 2, Defects: []ValidationError{{Field: "price"}, {Field: "title"}}},
+		{URL: "http://b", TotalProducts: 2},
+		{URL: "http://a", TotalProducts: 2, DefectCount: 1, Defects: []ValidationError{{Field: "title"}}},
+	}
+	for _, run := range runs {
+		if err := appendHistory(filename, run); err != nil {
+			t.Fatalf("Failed to append history: %v", err)
+		}
+	}
+
+	all, err := loadHistory(filename, 0, "")
+	if err != nil || len(all) != 3 {
+		t.Fatalf("Expected 3 runs, got %d (%v)", len(all), err)
+	}
+	last, _ := loadHistory(filename, 1, "")
+	if len(last) != 1 || last[0].DefectCount != 1 {
+		t.Errorf("Expected only the most recent run, got %+v", last)
+	}
+	filtered, _ := loadHistory(filename, 10, "http://a")
+	if len(filtered) != 2 {
+		t.Fatalf("Expected 2 runs for http://a, got %d", len(filtered))
+	}
+
+	var out bytes.Buffer
+	printHistory(&out, filtered)
+	for _, expected := range []string{"price", "improving (-1)", "title", "stable"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected history output to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+}
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPathNode is a value selected by a JSONPath expression together with its location
+type jsonPathNode struct {
+	Path  string
+	Value interface{}
+}
+
+// jsonPathSegment is one step of a parsed JSONPath expression
+type jsonPathSegment struct {
+	kind   string // "name", "index", "wildcard", "filter" or "descendant"
+	name   string
+	index  int
+	filter string
+}
+
+// evaluateJSONPath selects the nodes of a decoded JSON document matched by expr.
+// Supported syntax: $, .name, ['name'], [n] (negative counts from the end), [*], .*,
+// ..name (recursive descent) and filters such as [?(@.price > 10 && @.category == 'jewelery')]
+func evaluateJSONPath(expr string, doc interface{}) ([]jsonPathNode, error) {
+	segments, err := parseJSONPath(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []jsonPathNode{{Path: "$", Value: doc}}
+	for _, segment := range segments {
+		var next []jsonPathNode
+		for _, node := range nodes {
+			selected, err := applyJSONPathSegment(segment, node)
+			if err != nil {
+				return nil, fmt.Errorf("JSONPath %q: %w", expr, err)
+			}
+			next = append(next, selected...)
+		}
+		nodes = next
+	}
+
+	return nodes, nil
+}
+
+// parseJSONPath splits an expression into segments
+func parseJSONPath(expr string) ([]jsonPathSegment, error) {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("JSONPath %q must start with $", expr)
+	}
+
+	var segments []jsonPathSegment
+	rest := expr[1:]
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			// Recursive descent is followed by a name, a wildcard or a bracket
+			segments = append(segments, jsonPathSegment{kind: "descendant"})
+			rest = rest[1:]
+			if strings.HasPrefix(rest, ".[") {
+				rest = rest[1:]
+			}
+		case strings.HasPrefix(rest, ".*"):
+			segments = append(segments, jsonPathSegment{kind: "wildcard"})
+			rest = rest[2:]
+		case rest[0] == '.':
+			end := 1
+			for end < len(rest) && rest[end] != '.' && rest[end] != '[' {
+				end++
+			}
+			if end == 1 {
+				return nil, fmt.Errorf("JSONPath %q has an empty name", expr)
+			}
+			segments = append(segments, jsonPathSegment{kind: "name", name: rest[1:end]})
+			rest = rest[end:]
+		case rest[0] == '[':
+			end := matchingBracket(rest)
+			if end < 0 {
+				return nil, fmt.Errorf("JSONPath %q has an unclosed bracket", expr)
+			}
+			segment, err := parseJSONPathBracket(strings.TrimSpace(rest[1:end]))
+			if err != nil {
+				return nil, fmt.Errorf("JSONPath %q: %w", expr, err)
+			}
+			segments = append(segments, segment)
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("JSONPath %q: unexpected %q", expr, rest)
+		}
+	}
+
+	return segments, nil
+}
+
+// matchingBracket returns the index of the ] closing the [ at the start of s, skipping quoted text
+func matchingBracket(s string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseJSONPathBracket parses the inside of a [...] selector
+func parseJSONPathBracket(inner string) (jsonPathSegment, error) {
+	if inner == "*" {
+		return jsonPathSegment{kind: "wildcard"}, nil
+	}
+	if strings.HasPrefix(inner, "?") {
+		filter := strings.TrimSpace(inner[1:])
+		if !strings.HasPrefix(filter, "(") || !strings.HasSuffix(filter, ")") {
+			return jsonPathSegment{}, fmt.Errorf("filter %q must be wrapped in ?( )", inner)
+		}
+		return jsonPathSegment{kind: "filter", filter: filter[1 : len(filter)-1]}, nil
+	}
+	if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+		return jsonPathSegment{kind: "name", name: inner[1 : len(inner)-1]}, nil
+	}
+
+	index, err := strconv.Atoi(inner)
+	if err != nil {
+		return jsonPathSegment{}, fmt.Errorf("unsupported selector [%s]", inner)
+	}
+	return jsonPathSegment{kind: "index", index: index}, nil
+}
+
+// applyJSONPathSegment returns the children of node selected by segment
+func applyJSONPathSegment(segment jsonPathSegment, node jsonPathNode) ([]jsonPathNode, error) {
+	switch segment.kind {
+	case "name":
+		if obj, ok := node.Value.(map[string]interface{}); ok {
+			if value, ok := obj[segment.name]; ok {
+				return []jsonPathNode{{Path: node.Path + "." + segment.name, Value: value}}, nil
+			}
+		}
+	case "index":
+		if arr, ok := node.Value.([]interface{}); ok {
+			index := segment.index
+			if index < 0 {
+				index += len(arr)
+			}
+			if index >= 0 && index < len(arr) {
+				return []jsonPathNode{{Path: fmt.Sprintf("%s[%d]", node.Path, index), Value: arr[index]}}, nil
+			}
+		}
+	case "wildcard":
+		return jsonPathChildren(node), nil
+	case "descendant":
+		return jsonPathDescendants(node), nil
+	case "filter":
+		var matched []jsonPathNode
+		for _, child := range jsonPathChildren(node) {
+			ok, err := evaluateJSONPathFilter(segment.filter, child.Value)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				matched = append(matched, child)
+			}
+		}
+		return matched, nil
+	}
+
+	return nil, nil
+}
+
+// jsonPathChildren lists the direct children of an object or array node
+func jsonPathChildren(node jsonPathNode) []jsonPathNode {
+	var children []jsonPathNode
+	switch value := node.Value.(type) {
+	case []interface{}:
+		for i, item := range value {
+			children = append(children, jsonPathNode{Path: fmt.Sprintf("%s[%d]", node.Path, i), Value: item})
+		}
+	case map[string]interface{}:
+		for _, key := range sortedKeys(value) {
+			children = append(children, jsonPathNode{Path: node.Path + "." + key, Value: value[key]})
+		}
+	}
+	return children
+}
+
+// jsonPathDescendants lists a node and all nodes below it, depth first
+func jsonPathDescendants(node jsonPathNode) []jsonPathNode {
+	nodes := []jsonPathNode{node}
+	for _, child := range jsonPathChildren(node) {
+		nodes = append(nodes, jsonPathDescendants(child)...)
+	}
+	return nodes
+}
+
+// sortedKeys returns the keys of an object in a stable order
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// evaluateJSONPathFilter evaluates a filter expression with @ bound to current.
+// Conditions are comparisons or existence checks joined by && and ||
+func evaluateJSONPathFilter(filter string, current interface{}) (bool, error) {
+	for _, alternative := range splitTopLevel(filter, "||") {
+		all := true
+		for _, condition := range splitTopLevel(alternative, "&&") {
+			ok, err := evaluateFilterCondition(strings.TrimSpace(condition), current)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// evaluateFilterCondition evaluates a single comparison or existence check
+func evaluateFilterCondition(condition string, current interface{}) (bool, error) {
+	condition = strings.TrimSpace(condition)
+	for strings.HasPrefix(condition, "(") && strings.HasSuffix(condition, ")") {
+		condition = strings.TrimSpace(condition[1 : len(condition)-1])
+	}
+
+	left, op, right, found := splitComparison(condition)
+	if !found {
+		// A bare @ path checks that the value exists
+		values, err := filterOperandValues(condition, current)
+		if err != nil {
+			return false, err
+		}
+		return len(values) > 0, nil
+	}
+
+	leftValues, err := filterOperandValues(left, current)
+	if err != nil {
+		return false, err
+	}
+	rightValues, err := filterOperandValues(right, current)
+	if err != nil {
+		return false, err
+	}
+	if len(leftValues) == 0 || len(rightValues) == 0 {
+		return false, nil
+	}
+
+	return compareJSONValues(leftValues[0], op, rightValues[0])
+}
+
+// filterOperandValues resolves an @ path or a literal inside a filter
+func filterOperandValues(operand string, current interface{}) ([]interface{}, error) {
+	operand = strings.TrimSpace(operand)
+	if strings.HasPrefix(operand, "@") {
+		nodes, err := evaluateJSONPath("$"+operand[1:], current)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, len(nodes))
+		for i, node := range nodes {
+			values[i] = node.Value
+		}
+		return values, nil
+	}
+
+	value, err := parseJSONLiteral(operand)
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{value}, nil
+}
+
+// comparisonOperators are checked longest first so ">=" isn't read as ">"
+var comparisonOperators = []string{"==", "!=", ">=", "<=", ">", "<"}
+
+// splitComparison splits "left op right" at the first operator outside quotes
+func splitComparison(expr string) (string, string, string, bool) {
+	var quote byte
+	depth := 0
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+			continue
+		case c == '\'' || c == '"':
+			quote = c
+			continue
+		case c == '(' || c == '[':
+			depth++
+			continue
+		case c == ')' || c == ']':
+			depth--
+			continue
+		}
+		if depth > 0 {
+			continue
+		}
+		for _, op := range comparisonOperators {
+			if strings.HasPrefix(expr[i:], op) {
+				return strings.TrimSpace(expr[:i]), op, strings.TrimSpace(expr[i+len(op):]), true
+			}
+		}
+	}
+	return "", "", "", false
+}
+
+// splitTopLevel splits s on sep where sep is outside quotes and parentheses
+func splitTopLevel(s, sep string) []string {
+	var parts []string
+	var quote byte
+	depth := 0
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case depth == 0 && strings.HasPrefix(s[i:], sep):
+			parts = append(parts, s[start:i])
+			start = i + len(sep)
+			i += len(sep) - 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseJSONLiteral parses a number, quoted string, true, false or null
+func parseJSONLiteral(literal string) (interface{}, error) {
+	literal = strings.TrimSpace(literal)
+	switch literal {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if len(literal) >= 2 && (literal[0] == '\'' || literal[0] == '"') && literal[len(literal)-1] == literal[0] {
+		return literal[1 : len(literal)-1], nil
+	}
+	n, err := strconv.ParseFloat(literal, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid literal %q", literal)
+	}
+	return n, nil
+}
+
+// compareJSONValues applies a comparison operator to two decoded JSON values
+func compareJSONValues(left interface{}, op string, right interface{}) (bool, error) {
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return op == "!=", nil
+		}
+		switch op {
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		case ">":
+			return l > r, nil
+		case ">=":
+			return l >= r, nil
+		case "<":
+			return l < r, nil
+		case "<=":
+			return l <= r, nil
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return op == "!=", nil
+		}
+		switch op {
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		case ">":
+			return l > r, nil
+		case ">=":
+			return l >= r, nil
+		case "<":
+			return l < r, nil
+		case "<=":
+			return l <= r, nil
+		}
+	default:
+		switch op {
+		case "==":
+			return fmt.Sprint(left) == fmt.Sprint(right), nil
+		case "!=":
+			return fmt.Sprint(left) != fmt.Sprint(right), nil
+		}
+		return false, fmt.Errorf("operator %s is not supported for %v", op, left)
+	}
+
+	return false, fmt.Errorf("unknown operator %q", op)
+}
@@ -15,6 +15,9 @@ import (
 // API endpoint URL (variable for testing)
 var apiURL = "https://fakestoreapi.com/products"
 
+// HTTP client used for every request the tester makes
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
 // Product represents a product from the FakeStore API
 type Product struct {
 	ID          int     `json:"id"`
@@ -24,6 +27,20 @@ type Product struct {
 	Category    string  `json:"category"`
 	Image       string  `json:"image"`
 	Rating      Rating  `json:"rating"`
+	// Currency is an optional ISO 4217 code; prices without one use the configured default
+	Currency string `json:"currency,omitempty"`
+	// Raw is the product's JSON as received, including fields the struct doesn't model
+	Raw json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes a product and keeps its raw JSON
+func (p *Product) UnmarshalJSON(data []byte) error {
+	type plainProduct Product
+	if err := json.Unmarshal(data, (*plainProduct)(p)); err != nil {
+		return err
+	}
+	p.Raw = append(json.RawMessage(nil), data...)
+	return nil
 }
 
 // Rating represents the rating information for a product
@@ -38,138 +55,326 @@ type ValidationError struct {
 	Title       string      `json:"title"`
 	Field       string      `json:"field"`
 	Message     string      `json:"message"`
+	Severity    string      `json:"severity"`
 	ActualValue interface{} `json:"actual_value"`
+	// Index is the position of the product in the response, which unlike its ID is unique
+	Index int `json:"-"`
 }
 
+// Severity levels of validation errors, used to weight the data-quality score
+const (
+	SeverityCritical = "critical"
+	SeverityMajor    = "major"
+	SeverityMinor    = "minor"
+)
+
 // TestReport represents the overall test results
 type TestReport struct {
-	Timestamp       string            `json:"timestamp"`
-	URL             string            `json:"url"`
-	StatusCode      int               `json:"status_code"`
-	StatusCodeValid bool              `json:"status_code_valid"`
-	TotalProducts   int               `json:"total_products"`
-	DefectCount     int               `json:"defect_count"`
-	Defects         []ValidationError `json:"defects"`
+	Timestamp       string              `json:"timestamp"`
+	URL             string              `json:"url"`
+	StatusCode      int                 `json:"status_code"`
+	StatusCodeValid bool                `json:"status_code_valid"`
+	TotalProducts   int                 `json:"total_products"`
+	DefectCount     int                 `json:"defect_count"`
+	QualityScore    float64             `json:"quality_score"`
+	Defects         []ValidationError   `json:"defects"`
+	GraphQLErrors   []GraphQLError      `json:"graphql_errors,omitempty"`
+	Assertions      []AssertionResult   `json:"assertions,omitempty"`
+	Snapshot        *SnapshotResult     `json:"snapshot,omitempty"`
+	Scenarios       []ScenarioResult    `json:"scenarios,omitempty"`
+	TLS             *TLSInfo            `json:"tls,omitempty"`
+	HeaderChecks    []HeaderCheckResult `json:"header_checks,omitempty"`
 }
 
 func main() {
+	// Subcommands are dispatched before the regular flags are parsed
+	if len(os.Args) > 1 && os.Args[1] == "history" {
+		runHistoryCommand(os.Args[2:])
+		return
+	}
+
 	// Parse command line flags
 	jsonOutput := flag.String("json", "", "Output JSON report to specified file")
 	mockServer := flag.Bool("mock", false, "Run with mock server containing defective data")
-	mockPort := flag.Int("port", 8080, "Port for mock server")
+	mockPort := flag.Int("port", 8080, "Port for mock server (0 picks a free port)")
+	openAPIFile := flag.String("openapi", "", "Run a mock server generated from the given OpenAPI (JSON) file")
+	openAPIValidate := flag.Bool("openapi-validate", false, "Reject requests to the OpenAPI mock server that don't conform to the spec")
+	suiteFile := flag.String("suite", "", "Load test suite definition from the given JSON file")
+	updateSnapshots := flag.Bool("update-snapshots", false, "Save the normalized response body as the new snapshot")
+	snapshotDir := flag.String("snapshot-dir", "snapshots", "Directory holding response snapshots")
+	snapshotIgnore := flag.String("snapshot-ignore", "", "Comma-separated JSONPaths excluded from snapshots")
+	envList := flag.String("env", "", "Run against several environments concurrently, e.g. dev=URL,prod=URL")
+	harOutput := flag.String("har", "", "Export all HTTP traffic to the specified HAR file")
+	historyFile := flag.String("history", "", "Append the report to the specified JSONL history file")
+	tuiMode := flag.Bool("tui", false, "Browse the defects interactively after the run")
+	proxy := flag.String("proxy", "", "Proxy URL, overriding HTTP_PROXY/HTTPS_PROXY")
+	caCert := flag.String("cacert", "", "PEM bundle of additional trusted CA certificates")
+	clientCert := flag.String("cert", "", "PEM client certificate for mutual TLS")
+	clientKey := flag.String("key", "", "PEM private key for the client certificate")
+	insecure := flag.Bool("insecure", false, "Skip TLS certificate verification")
+	priceChecks := flag.Bool("validate-prices", false, "Check prices against their currency's precision, also enabled by a suite's validation.prices section")
+	textChecks := flag.Bool("validate-text", false, "Check titles and descriptions for HTML, placeholders and other text defects, also enabled by a suite's validation.text section")
 	flag.Parse()
 
+	// Configure proxy and TLS settings for every request
+	transport, err := buildTransport(transportOptions{
+		Proxy:    *proxy,
+		CACert:   *caCert,
+		Cert:     *clientCert,
+		Key:      *clientKey,
+		Insecure: *insecure,
+	})
+	if err != nil {
+		fmt.Printf("Error configuring transport: %v\n", err)
+		os.Exit(1)
+	}
+	httpClient.Transport = transport
+
+	// Record every request and response when a HAR export is requested; the recorder
+	// wraps the configured transport so proxy and TLS settings still apply
+	var recorder *harRecorder
+	if *harOutput != "" {
+		recorder = newHARRecorder(httpClient.Transport)
+		httpClient.Transport = recorder
+	}
+
+	// Load the suite, if any, and point the tester at its URL
+	var suite *Suite
+	if *suiteFile != "" {
+		var err error
+		suite, err = loadSuite(*suiteFile)
+		if err != nil {
+			fmt.Printf("Error loading suite: %v\n", err)
+			os.Exit(1)
+		}
+		if suite.URL != "" {
+			apiURL = suite.URL
+		}
+	}
+
+	// The suite's validation rules, with the checks enabled by flags added
+	var validation ValidationConfig
+	if suite != nil && suite.Validation != nil {
+		validation = *suite.Validation
+	}
+	if *priceChecks && validation.Prices == nil {
+		validation.Prices = &PriceRules{}
+	}
+	if *textChecks && validation.Text == nil {
+		validation.Text = &TextRules{}
+	}
+
+	// Load the OpenAPI spec before starting anything so errors are reported early
+	var spec *OpenAPISpec
+	if *openAPIFile != "" {
+		var err error
+		spec, err = loadOpenAPISpec(*openAPIFile)
+		if err != nil {
+			fmt.Printf("Error loading OpenAPI spec: %v\n", err)
+			os.Exit(1)
+		}
+		*mockServer = true
+	}
+
 	// Run mock server if requested
+	var mock *MockServer
 	if *mockServer {
-		// Update URL to point to local mock server
-		apiURL = fmt.Sprintf("http://localhost:%d/products", *mockPort)
-
-		// Launch mock server in a goroutine
-		go RunMockServer(*mockPort)
+		if spec != nil {
+			mock = NewMockServer(newOpenAPIHandler(spec, *openAPIValidate))
+		} else {
+			mock = NewMockServer(newMockHandler(newMockStore()))
+		}
+		if err := mock.Start(*mockPort); err != nil {
+			fmt.Printf("Error starting mock server: %v\n", err)
+			os.Exit(1)
+		}
 
-		// Give it a moment to start
-		time.Sleep(100 * time.Millisecond)
+		// Update URL to point to local mock server, whose port may have been chosen dynamically
+		apiURL = mock.URL() + "/products"
+		if spec != nil {
+			fmt.Printf("Started OpenAPI mock server at %s\n", mock.URL())
+		} else {
+			fmt.Printf("Started mock server at %s\n", apiURL)
+			fmt.Printf("Admin API available at %s/admin/\n", mock.URL())
+		}
 	}
 
 	fmt.Println("API Tester - FakeStore API Validation")
 	fmt.Println("=====================================")
 	fmt.Println()
 
-	// Display which API we're testing
-	fmt.Printf("Testing API: %s\n\n", apiURL)
-
-	// Initialize test report
-	report := TestReport{
-		Timestamp: time.Now().Format(time.RFC3339),
-		URL:       apiURL,
+	opts := runOptions{
+		Suite:           suite,
+		UpdateSnapshots: *updateSnapshots,
+		SnapshotDir:     *snapshotDir,
+		SnapshotIgnore:  splitList(*snapshotIgnore),
+		InsecureTLS:     *insecure,
+		Validation:      validation,
 	}
 
-	// Fetch data from API
-	products, statusCode, err := fetchProducts()
-	if err != nil {
-		fmt.Printf("Error fetching products: %v\n", err)
-		os.Exit(1)
+	// Environments from the flag take precedence over the suite's
+	var envs []Environment
+	if *envList != "" {
+		var err error
+		envs, err = parseEnvironments(*envList)
+		if err != nil {
+			fmt.Printf("Error parsing environments: %v\n", err)
+			os.Exit(1)
+		}
+	} else if suite != nil && len(suite.Environments) > 0 {
+		envs = suiteEnvironments(suite)
 	}
 
-	// Test 1: Verify server response code
-	fmt.Println("Test 1: Verify server response code")
-	fmt.Printf("Status Code: %d\n", statusCode)
-	report.StatusCode = statusCode
-	report.StatusCodeValid = (statusCode == http.StatusOK)
-
-	if report.StatusCodeValid {
-		fmt.Println("✅ Status code is 200 OK")
-	} else {
-		fmt.Printf("❌ Expected status code 200, got %d\n", statusCode)
-	}
-	fmt.Println()
+	if len(envs) > 0 {
+		multiReport := runEnvironments(envs, opts)
+
+		var names []string
+		for _, env := range multiReport.Environments {
+			fmt.Printf("=== Environment: %s ===\n", env.Name)
+			fmt.Printf("Testing API: %s\n\n", env.URL)
+			if env.Error != "" {
+				fmt.Printf("Error fetching products: %s\n\n", env.Error)
+				continue
+			}
+			names = append(names, env.Name)
+			printReport(*env.Report, suite)
+			fmt.Println()
+		}
 
-	// Validate products and collect errors
-	fmt.Println("Test 2: Validate product attributes")
-	validationErrors := validateProducts(products)
+		fmt.Println("Environment Comparison")
+		fmt.Println("----------------------")
+		if len(multiReport.Differences) > 0 {
+			printEnvironmentDifferences(names, multiReport.Differences)
+		} else {
+			fmt.Println("✅ Products are identical in all environments")
+		}
 
-	// Update report
-	report.TotalProducts = len(products)
-	report.DefectCount = len(validationErrors)
-	report.Defects = validationErrors
+		// Output JSON report if requested
+		if *jsonOutput != "" {
+			generateJSONReport(*jsonOutput, multiReport)
+		}
+		if *historyFile != "" {
+			for _, env := range multiReport.Environments {
+				if env.Report != nil {
+					recordHistory(*historyFile, *env.Report)
+				}
+			}
+		}
+		if recorder != nil {
+			generateHARFile(*harOutput, recorder)
+		}
+		return
+	}
 
-	// Display validation results
-	fmt.Printf("Total products: %d\n", report.TotalProducts)
-	fmt.Printf("Products with defects: %d\n", report.DefectCount)
-	fmt.Println()
+	// Display which API we're testing
+	fmt.Printf("Testing API: %s\n\n", apiURL)
 
-	// Display the list of defects
-	if report.DefectCount > 0 {
-		fmt.Println("Defective Products:")
-		fmt.Println("-----------------")
-		printValidationErrors(validationErrors)
-	} else {
-		fmt.Println("✅ No defects found in any products")
+	report, products, err := runTests(apiURL, opts)
+	if err != nil {
+		fmt.Printf("Error fetching products: %v\n", err)
+		// The traffic of a failed run is the most useful for debugging
+		if recorder != nil {
+			generateHARFile(*harOutput, recorder)
+		}
+		os.Exit(1)
 	}
+	printReport(report, suite)
 
 	// Output JSON report if requested
 	if *jsonOutput != "" {
 		generateJSONReport(*jsonOutput, report)
 	}
 
-	// If running mock server, don't exit immediately
-	if *mockServer {
+	// Record the run for trend analysis if requested
+	if *historyFile != "" {
+		recordHistory(*historyFile, report)
+	}
+
+	// Output HAR file if requested
+	if recorder != nil {
+		generateHARFile(*harOutput, recorder)
+	}
+
+	// Browse the defects interactively if requested
+	if *tuiMode && len(report.Defects) > 0 {
+		if err := runDefectBrowser(os.Stdin, os.Stdout, report.Defects, products); err != nil {
+			fmt.Printf("Error running defect browser: %v\n", err)
+		}
+	}
+
+	// If running mock server, keep serving until interrupted
+	if mock != nil {
 		fmt.Println("\nMock server is running. Press Ctrl+C to exit.")
-		// Block to keep the server running
-		select {}
+		mock.waitForShutdownSignal()
 	}
 }
 
 // fetchProducts retrieves products from the API
 func fetchProducts() ([]Product, int, error) {
+	resp, body, err := fetchResponse()
+	if err != nil {
+		return nil, responseStatus(resp), err
+	}
+
+	products, err := parseProducts(body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+
+	return products, resp.StatusCode, nil
+}
+
+// fetchResponse retrieves the raw response body from the API
+func fetchResponse() (*http.Response, []byte, error) {
+	return fetchResponseFrom(apiURL)
+}
+
+// fetchResponseFrom retrieves the raw response body from the given URL. The returned
+// response carries the status, headers and TLS state; its body has already been read
+func fetchResponseFrom(url string) (*http.Response, []byte, error) {
 	// Make HTTP request
-	resp, err := http.Get(apiURL)
+	resp, err := httpClient.Get(url)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to make request: %w", err)
+		return nil, nil, fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, resp.StatusCode, fmt.Errorf("failed to read response body: %w", err)
+		return resp, nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	// Parse JSON
+	return resp, body, nil
+}
+
+// responseStatus returns the status code of a possibly missing response
+func responseStatus(resp *http.Response) int {
+	if resp == nil {
+		return 0
+	}
+	return resp.StatusCode
+}
+
+// parseProducts decodes a JSON array of products
+func parseProducts(body []byte) ([]Product, error) {
 	var products []Product
-	err = json.Unmarshal(body, &products)
+	err := json.Unmarshal(body, &products)
 	if err != nil {
-		return nil, resp.StatusCode, fmt.Errorf("failed to parse JSON: %w", err)
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
-	return products, resp.StatusCode, nil
+	return products, nil
 }
 
-// validateProducts checks all products for defects
-func validateProducts(products []Product) []ValidationError {
+// validateProducts checks all products for defects, including the optional price and text
+// checks the config enables
+func validateProducts(products []Product, config ValidationConfig) []ValidationError {
 	var errors []ValidationError
 
-	for _, product := range products {
+	for i, product := range products {
+		first := len(errors)
+
 		// Check for empty title
 		if product.Title == "" {
 			errors = append(errors, ValidationError{
@@ -177,6 +382,7 @@ func validateProducts(products []Product) []ValidationError {
 				Title:       product.Title,
 				Field:       "title",
 				Message:     "Title is empty",
+				Severity:    SeverityCritical,
 				ActualValue: product.Title,
 			})
 		} else if strings.TrimSpace(product.Title) == "" {
@@ -185,6 +391,7 @@ func validateProducts(products []Product) []ValidationError {
 				Title:       product.Title,
 				Field:       "title",
 				Message:     "Title contains only whitespace",
+				Severity:    SeverityMajor,
 				ActualValue: product.Title,
 			})
 		}
@@ -196,6 +403,7 @@ func validateProducts(products []Product) []ValidationError {
 				Title:       product.Title,
 				Field:       "price",
 				Message:     "Price is negative",
+				Severity:    SeverityCritical,
 				ActualValue: product.Price,
 			})
 		}
@@ -207,6 +415,7 @@ func validateProducts(products []Product) []ValidationError {
 				Title:       product.Title,
 				Field:       "rating.rate",
 				Message:     "Rating rate exceeds 5",
+				Severity:    SeverityMajor,
 				ActualValue: product.Rating.Rate,
 			})
 		}
@@ -218,6 +427,7 @@ func validateProducts(products []Product) []ValidationError {
 				Title:       product.Title,
 				Field:       "price",
 				Message:     "Price is zero",
+				Severity:    SeverityMajor,
 				ActualValue: product.Price,
 			})
 		}
@@ -229,6 +439,7 @@ func validateProducts(products []Product) []ValidationError {
 				Title:       product.Title,
 				Field:       "rating.count",
 				Message:     "Rating count is negative",
+				Severity:    SeverityMajor,
 				ActualValue: product.Rating.Count,
 			})
 		}
@@ -240,19 +451,45 @@ func validateProducts(products []Product) []ValidationError {
 				Title:       product.Title,
 				Field:       "description",
 				Message:     "Description is empty",
+				Severity:    SeverityMinor,
 				ActualValue: product.Description,
 			})
 		}
+
+		// Check precision and thresholds for the product's currency, when enabled
+		if config.Prices != nil {
+			errors = append(errors, validatePrice(product, *config.Prices)...)
+		}
+
+		// Check the quality of the title and description text, when enabled
+		if config.Text != nil {
+			errors = append(errors, validateText(product, *config.Text)...)
+		}
+
+		for j := first; j < len(errors); j++ {
+			errors[j].Index = i
+		}
 	}
 
 	return errors
 }
 
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // printValidationErrors displays validation errors in a formatted table
 func printValidationErrors(errors []ValidationError) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "ID\tTitle\tField\tIssue\tValue")
-	fmt.Fprintln(w, "--\t-----\t-----\t-----\t-----")
+	fmt.Fprintln(w, "ID\tTitle\tField\tSeverity\tIssue\tValue")
+	fmt.Fprintln(w, "--\t-----\t-----\t--------\t-----\t-----")
 
 	for _, err := range errors {
 		title := err.Title
@@ -261,10 +498,11 @@ func printValidationErrors(errors []ValidationError) {
 		} else if len(title) > 30 {
 			title = title[:27] + "..."
 		}
-		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%v\n",
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%v\n",
 			err.ProductID,
 			title,
 			err.Field,
+			err.Severity,
 			err.Message,
 			err.ActualValue,
 		)
@@ -273,7 +511,7 @@ func printValidationErrors(errors []ValidationError) {
 }
 
 // generateJSONReport outputs the test report as JSON to a file
-func generateJSONReport(filename string, report TestReport) {
+func generateJSONReport(filename string, report interface{}) {
 	// Marshal report to JSON
 	reportJSON, err := json.MarshalIndent(report, "", "  ")
 	if err != nil {
@@ -290,3 +528,23 @@ func generateJSONReport(filename string, report TestReport) {
 
 	fmt.Printf("\nJSON report written to %s\n", filename)
 }
+
+// generateHARFile writes the recorded traffic to a HAR file
+func generateHARFile(filename string, recorder *harRecorder) {
+	if err := recorder.writeHAR(filename); err != nil {
+		fmt.Printf("Error writing HAR file: %v\n", err)
+		return
+	}
+
+	fmt.Printf("\nHAR file written to %s\n", filename)
+}
+
+// recordHistory appends a report to the history file
+func recordHistory(filename string, report TestReport) {
+	if err := appendHistory(filename, report); err != nil {
+		fmt.Printf("Error recording history: %v\n", err)
+		return
+	}
+
+	fmt.Printf("\nRun recorded in %s\n", filename)
+}
@@ -185,7 +185,7 @@ func TestValidateProducts(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			// Call function under test
-			errors := validateProducts(tc.products)
+			errors := validateProducts(tc.products, ValidationConfig{})
 
 			// Check error count
 			if len(errors) != tc.expectedErrors {
@@ -193,4 +193,13 @@ func TestValidateProducts(t *testing.T) {
 			}
 		})
 	}
+
+	// Defects point at the product's position, since IDs can repeat
+	products := []Product{
+		{ID: 7, Title: "Valid", Price: 1, Description: "Test"},
+		{ID: 7, Title: "Duplicate", Price: -1, Description: "Test"},
+	}
+	if errors := validateProducts(products, ValidationConfig{}); len(errors) != 1 || errors[0].Index != 1 {
+		t.Errorf("Expected one defect for the product at index 1, got %+v", errors)
+	}
 }
@@ -1,10 +1,21 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 )
 
 // Product data with intentional defects
@@ -56,18 +67,267 @@ var mockProducts = []Product{
 	},
 }
 
-// RunMockServer starts a mock server with defective product data
-func RunMockServer(port int) {
-	http.HandleFunc("/products", func(w http.ResponseWriter, r *http.Request) {
+// mockRequestLimit caps the request log so a long-running mock server doesn't grow unbounded
+const mockRequestLimit = 1000
+
+// MockRequest is a request received by the mock server, as returned by /admin/requests
+type MockRequest struct {
+	Time    string      `json:"time"`
+	Method  string      `json:"method"`
+	Path    string      `json:"path"`
+	Query   string      `json:"query,omitempty"`
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// mockStore holds the mock server's products and request log; the admin API mutates it at runtime
+type mockStore struct {
+	mu       sync.Mutex
+	products []Product
+	requests []MockRequest
+}
+
+// newMockStore creates a store seeded with the default defective products
+func newMockStore() *mockStore {
+	store := &mockStore{}
+	store.reset()
+	return store
+}
+
+// reset restores the default products and clears the request log
+func (s *mockStore) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.products = append([]Product{}, mockProducts...)
+	s.requests = nil
+}
+
+// snapshot returns a copy of the current products
+func (s *mockStore) snapshot() []Product {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Product{}, s.products...)
+}
+
+// record adds a request to the log, dropping the oldest entry when full
+func (s *mockStore) record(r *http.Request, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, MockRequest{
+		Time:    time.Now().Format(time.RFC3339Nano),
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Query:   r.URL.RawQuery,
+		Headers: r.Header.Clone(),
+		Body:    string(body),
+	})
+	if len(s.requests) > mockRequestLimit {
+		s.requests = s.requests[len(s.requests)-mockRequestLimit:]
+	}
+}
+
+// newMockHandler serves the products and the /admin API backed by store
+func newMockHandler(store *mockStore) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/products", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 
 		// Encode the mock products to JSON
-		json.NewEncoder(w).Encode(mockProducts)
+		json.NewEncoder(w).Encode(store.snapshot())
+	})
+
+	mux.HandleFunc("/admin/products", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, store.snapshot())
+		case http.MethodPut:
+			// Replace every product
+			var products []Product
+			if err := json.NewDecoder(r.Body).Decode(&products); err != nil {
+				writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("body must be a JSON array of products: %v", err))
+				return
+			}
+			if products == nil {
+				products = []Product{}
+			}
+			store.mu.Lock()
+			store.products = products
+			store.mu.Unlock()
+			writeJSON(w, http.StatusOK, products)
+		case http.MethodPost:
+			// Add a product, assigning the next free ID when none is given
+			var product Product
+			if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
+				writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("body must be a JSON product: %v", err))
+				return
+			}
+			store.mu.Lock()
+			if product.ID == 0 {
+				for _, p := range store.products {
+					if p.ID >= product.ID {
+						product.ID = p.ID + 1
+					}
+				}
+				if product.ID == 0 {
+					product.ID = 1
+				}
+			}
+			store.products = append(store.products, product)
+			store.mu.Unlock()
+			writeJSON(w, http.StatusCreated, product)
+		default:
+			writeJSONError(w, http.StatusMethodNotAllowed, "use GET, PUT or POST")
+		}
+	})
+
+	mux.HandleFunc("/admin/products/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			writeJSONError(w, http.StatusMethodNotAllowed, "use DELETE")
+			return
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/admin/products/"))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "product id must be an integer")
+			return
+		}
+
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		for i, p := range store.products {
+			if p.ID == id {
+				store.products = append(store.products[:i], store.products[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("product %d not found", id))
+	})
+
+	mux.HandleFunc("/admin/reset", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "use POST")
+			return
+		}
+		store.reset()
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("/admin/requests", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			store.mu.Lock()
+			requests := append([]MockRequest{}, store.requests...)
+			store.mu.Unlock()
+			writeJSON(w, http.StatusOK, requests)
+		case http.MethodDelete:
+			store.mu.Lock()
+			store.requests = nil
+			store.mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeJSONError(w, http.StatusMethodNotAllowed, "use GET or DELETE")
+		}
+	})
+
+	// Log every request except those to the admin API itself
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/admin/") {
+			body, _ := io.ReadAll(r.Body)
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			store.record(r, body)
+		}
+		mux.ServeHTTP(w, r)
 	})
+}
+
+// writeJSON writes value as a JSON response
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// MockServer runs a mock handler on a local port until it is stopped
+type MockServer struct {
+	handler  http.Handler
+	server   *http.Server
+	listener net.Listener
+}
+
+// NewMockServer creates a server for handler; call Start to begin serving
+func NewMockServer(handler http.Handler) *MockServer {
+	return &MockServer{handler: handler}
+}
+
+// Start listens on port (0 picks a free port) and returns once the server accepts connections
+func (m *MockServer) Start(port int) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %d: %w", port, err)
+	}
+	m.listener = listener
+	m.server = &http.Server{Handler: m.handler}
+
+	go func() {
+		if err := m.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("Mock server stopped: %v", err)
+		}
+	}()
+
+	return m.waitReady(5 * time.Second)
+}
+
+// waitReady polls the listener until a connection succeeds or the timeout passes
+func (m *MockServer) waitReady(timeout time.Duration) error {
+	address := fmt.Sprintf("localhost:%d", m.Port())
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.DialTimeout("tcp", address, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("mock server at %s did not become ready: %w", address, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Port returns the port the server listens on, which is only known after Start for port 0
+func (m *MockServer) Port() int {
+	if m.listener == nil {
+		return 0
+	}
+	return m.listener.Addr().(*net.TCPAddr).Port
+}
+
+// URL returns the base URL of the running server
+func (m *MockServer) URL() string {
+	return fmt.Sprintf("http://localhost:%d", m.Port())
+}
+
+// Stop shuts the server down gracefully, waiting for active requests until ctx expires
+func (m *MockServer) Stop(ctx context.Context) error {
+	if m.server == nil {
+		return nil
+	}
+	return m.server.Shutdown(ctx)
+}
+
+// waitForShutdownSignal blocks until SIGINT or SIGTERM, then stops the server gracefully
+func (m *MockServer) waitForShutdownSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	sig := <-signals
 
-	// Start the server
-	addr := fmt.Sprintf(":%d", port)
-	fmt.Printf("Starting mock server at http://localhost%s/products\n", addr)
-	log.Fatal(http.ListenAndServe(addr, nil))
+	fmt.Printf("\nReceived %v, shutting down mock server...\n", sig)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.Stop(ctx); err != nil {
+		fmt.Printf("Error shutting down mock server: %v\n", err)
+	}
 }
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMockAdminAPI(t *testing.T) {
+	server := httptest.NewServer(newMockHandler(newMockStore()))
+	defer server.Close()
+
+	send := func(method, path, body string) *http.Response {
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		return resp
+	}
+	products := func() []Product {
+		resp := send(http.MethodGet, "/products", "")
+		defer resp.Body.Close()
+		var result []Product
+		json.NewDecoder(resp.Body).Decode(&result)
+		return result
+	}
+
+	if count := len(products()); count != len(mockProducts) {
+		t.Fatalf("Expected %d default products, got %d", len(mockProducts), count)
+	}
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedCount  int
+	}{
+		{"Replace products", http.MethodPut, "/admin/products", `[{"id":7,"title":"Only"}]`, http.StatusOK, 1},
+		{"Add product", http.MethodPost, "/admin/products", `{"title":"Added","price":5}`, http.StatusCreated, 2},
+		{"Delete product", http.MethodDelete, "/admin/products/7", "", http.StatusNoContent, 1},
+		{"Delete missing product", http.MethodDelete, "/admin/products/7", "", http.StatusNotFound, 1},
+		{"Invalid body", http.MethodPut, "/admin/products", `{`, http.StatusBadRequest, 1},
+		{"Reset", http.MethodPost, "/admin/reset", "", http.StatusNoContent, len(mockProducts)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := send(tc.method, tc.path, tc.body)
+			resp.Body.Close()
+			if resp.StatusCode != tc.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatus, resp.StatusCode)
+			}
+			if count := len(products()); count != tc.expectedCount {
+				t.Errorf("Expected %d products, got %d", tc.expectedCount, count)
+			}
+		})
+	}
+
+	// The added product got the next free ID
+	send(http.MethodPut, "/admin/products", `[{"id":7,"title":"Only"}]`).Body.Close()
+	resp := send(http.MethodPost, "/admin/products", `{"title":"Added"}`)
+	var added Product
+	json.NewDecoder(resp.Body).Decode(&added)
+	resp.Body.Close()
+	if added.ID != 8 {
+		t.Errorf("Expected assigned ID 8, got %d", added.ID)
+	}
+}
+
+func TestMockRequestLog(t *testing.T) {
+	server := httptest.NewServer(newMockHandler(newMockStore()))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/products?limit=2", nil)
+	req.Header.Set("X-Client", "api_tester")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	resp, err = http.Get(server.URL + "/admin/requests")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	var requests []MockRequest
+	json.NewDecoder(resp.Body).Decode(&requests)
+	resp.Body.Close()
+
+	// Admin requests are not logged
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 logged request, got %d", len(requests))
+	}
+	logged := requests[0]
+	if logged.Path != "/products" || logged.Query != "limit=2" || logged.Headers.Get("X-Client") != "api_tester" {
+		t.Errorf("Unexpected logged request %+v", logged)
+	}
+
+	clearReq, _ := http.NewRequest(http.MethodDelete, server.URL+"/admin/requests", nil)
+	resp, _ = http.DefaultClient.Do(clearReq)
+	resp.Body.Close()
+	resp, _ = http.Get(server.URL + "/admin/requests")
+	requests = nil
+	json.NewDecoder(resp.Body).Decode(&requests)
+	resp.Body.Close()
+	if len(requests) != 0 {
+		t.Errorf("Expected empty log after clearing, got %d entries", len(requests))
+	}
+}
+
+func TestMockServerStartStop(t *testing.T) {
+	mock := NewMockServer(newMockHandler(newMockStore()))
+	if err := mock.Start(0); err != nil {
+		t.Fatalf("Failed to start mock server: %v", err)
+	}
+	if mock.Port() == 0 {
+		t.Fatalf("Expected a dynamically chosen port")
+	}
+
+	resp, err := http.Get(mock.URL() + "/products")
+	if err != nil {
+		t.Fatalf("Mock server is not reachable after Start: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := mock.Stop(ctx); err != nil {
+		t.Fatalf("Failed to stop mock server: %v", err)
+	}
+	if _, err := http.Get(mock.URL() + "/products"); err == nil {
+		t.Errorf("Expected requests to fail after Stop")
+	}
+}
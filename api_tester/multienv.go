@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Environment is a named base URL the suite is run against
+type Environment struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// EnvironmentReport holds the outcome of the suite in one environment
+type EnvironmentReport struct {
+	Name   string      `json:"name"`
+	URL    string      `json:"url"`
+	Report *TestReport `json:"report,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// EnvironmentDifference is a product field whose value is not the same in every environment
+type EnvironmentDifference struct {
+	ProductID int                    `json:"product_id"`
+	Title     string                 `json:"title"`
+	Field     string                 `json:"field"`
+	Values    map[string]interface{} `json:"values"`
+}
+
+// MultiEnvironmentReport combines the reports of all environments with their differences
+type MultiEnvironmentReport struct {
+	Timestamp    string                  `json:"timestamp"`
+	Environments []EnvironmentReport     `json:"environments"`
+	Differences  []EnvironmentDifference `json:"differences"`
+}
+
+// parseEnvironments parses a "name=url,name=url" flag value, keeping the given order
+func parseEnvironments(value string) ([]Environment, error) {
+	var envs []Environment
+	seen := map[string]bool{}
+	for _, item := range splitList(value) {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("invalid environment %q, expected name=url", item)
+		}
+		name := strings.TrimSpace(parts[0])
+		if seen[name] {
+			return nil, fmt.Errorf("environment %q is listed twice", name)
+		}
+		seen[name] = true
+		envs = append(envs, Environment{Name: name, URL: strings.TrimSpace(parts[1])})
+	}
+	return envs, nil
+}
+
+// suiteEnvironments returns the environments declared in a suite, sorted by name
+func suiteEnvironments(suite *Suite) []Environment {
+	names := make([]string, 0, len(suite.Environments))
+	for name := range suite.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	envs := make([]Environment, 0, len(names))
+	for _, name := range names {
+		envs = append(envs, Environment{Name: name, URL: suite.Environments[name]})
+	}
+	return envs
+}
+
+// runEnvironments runs the suite against every environment concurrently and compares the products
+func runEnvironments(envs []Environment, opts runOptions) MultiEnvironmentReport {
+	reports := make([]EnvironmentReport, len(envs))
+	products := make([][]Product, len(envs))
+
+	var wg sync.WaitGroup
+	for i, env := range envs {
+		wg.Add(1)
+		go func(i int, env Environment) {
+			defer wg.Done()
+
+			envOpts := opts
+			if opts.Suite != nil && opts.Suite.Name != "" {
+				envOpts.SnapshotName = opts.Suite.Name + "-" + env.Name
+			}
+
+			reports[i] = EnvironmentReport{Name: env.Name, URL: env.URL}
+			report, envProducts, err := runTests(env.URL, envOpts)
+			if err != nil {
+				reports[i].Error = err.Error()
+				return
+			}
+			reports[i].Report = &report
+			products[i] = envProducts
+		}(i, env)
+	}
+	wg.Wait()
+
+	// Only environments that answered take part in the comparison
+	var names []string
+	byEnv := map[string][]Product{}
+	for i, report := range reports {
+		if report.Error == "" {
+			names = append(names, report.Name)
+			byEnv[report.Name] = products[i]
+		}
+	}
+
+	return MultiEnvironmentReport{
+		Timestamp:    time.Now().Format(time.RFC3339),
+		Environments: reports,
+		Differences:  compareEnvironments(names, byEnv),
+	}
+}
+
+// productField is a named product value compared across environments
+type productField struct {
+	name  string
+	value interface{}
+}
+
+// productFields lists the compared fields of a product in display order
+func productFields(p Product) []productField {
+	return []productField{
+		{"title", p.Title},
+		{"price", p.Price},
+		{"description", p.Description},
+		{"category", p.Category},
+		{"image", p.Image},
+		{"rating.rate", p.Rating.Rate},
+		{"rating.count", p.Rating.Count},
+		{"currency", p.Currency},
+	}
+}
+
+// compareEnvironments finds products missing from some environments and fields whose values differ
+func compareEnvironments(names []string, products map[string][]Product) []EnvironmentDifference {
+	// Index products by ID per environment
+	indexed := map[string]map[int]Product{}
+	var ids []int
+	seenIDs := map[int]bool{}
+	for _, name := range names {
+		indexed[name] = map[int]Product{}
+		for _, p := range products[name] {
+			indexed[name][p.ID] = p
+			if !seenIDs[p.ID] {
+				seenIDs[p.ID] = true
+				ids = append(ids, p.ID)
+			}
+		}
+	}
+	sort.Ints(ids)
+
+	var diffs []EnvironmentDifference
+	for _, id := range ids {
+		// Use the first environment that has the product as the reference
+		var reference Product
+		found := false
+		presence := map[string]interface{}{}
+		missing := false
+		for _, name := range names {
+			if p, ok := indexed[name][id]; ok {
+				if !found {
+					reference = p
+					found = true
+				}
+				presence[name] = "present"
+			} else {
+				presence[name] = "missing"
+				missing = true
+			}
+		}
+		if missing {
+			diffs = append(diffs, EnvironmentDifference{ProductID: id, Title: reference.Title, Field: "product", Values: presence})
+		}
+
+		for i, field := range productFields(reference) {
+			values := map[string]interface{}{}
+			differs := false
+			for _, name := range names {
+				p, ok := indexed[name][id]
+				if !ok {
+					continue
+				}
+				value := productFields(p)[i].value
+				values[name] = value
+				if value != field.value {
+					differs = true
+				}
+			}
+			if differs {
+				diffs = append(diffs, EnvironmentDifference{ProductID: id, Title: reference.Title, Field: field.name, Values: values})
+			}
+		}
+	}
+
+	return diffs
+}
+
+// printEnvironmentDifferences displays differences as a table with one column per environment
+func printEnvironmentDifferences(names []string, diffs []EnvironmentDifference) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ID\tTitle\tField\t%s\n", strings.Join(names, "\t"))
+	fmt.Fprintf(w, "--\t-----\t-----\t%s\n", strings.TrimSuffix(strings.Repeat("-----\t", len(names)), "\t"))
+
+	for _, diff := range diffs {
+		title := diff.Title
+		if title == "" {
+			title = "<empty>"
+		} else if len(title) > 30 {
+			title = title[:27] + "..."
+		}
+		values := make([]string, len(names))
+		for i, name := range names {
+			if value, ok := diff.Values[name]; ok {
+				values[i] = fmt.Sprint(value)
+			} else {
+				values[i] = "-"
+			}
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", diff.ProductID, title, diff.Field, strings.Join(values, "\t"))
+	}
+	w.Flush()
+}
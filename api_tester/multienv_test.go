@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseEnvironments(t *testing.T) {
+	envs, err := parseEnvironments("dev=http://dev.local/products, prod=https://shop.example.com/products")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(envs) != 2 || envs[0].Name != "dev" || envs[1].URL != "https://shop.example.com/products" {
+		t.Errorf("Unexpected environments %+v", envs)
+	}
+
+	for _, value := range []string{"dev", "=http://x", "dev=http://a,dev=http://b"} {
+		if _, err := parseEnvironments(value); err == nil {
+			t.Errorf("Expected error for %q, got nil", value)
+		}
+	}
+}
+
+func TestRunEnvironments(t *testing.T) {
+	staging := setupMockServer(t, http.StatusOK, `[
+		{"id":1,"title":"Backpack","price":109.95,"description":"d","rating":{"rate":3.9,"count":120}},
+		{"id":2,"title":"Shirt","price":22.3,"description":"d","rating":{"rate":4.1,"count":259}}
+	]`)
+	defer staging.Close()
+	prod := setupMockServer(t, http.StatusOK, `[
+		{"id":1,"title":"Backpack","price":99.95,"description":"d","rating":{"rate":3.9,"count":120},"currency":"EUR"}
+	]`)
+	defer prod.Close()
+
+	envs := []Environment{
+		{Name: "staging", URL: staging.URL},
+		{Name: "prod", URL: prod.URL},
+		{Name: "down", URL: "http://127.0.0.1:1/products"},
+	}
+	report := runEnvironments(envs, runOptions{SnapshotDir: t.TempDir()})
+
+	if len(report.Environments) != 3 {
+		t.Fatalf("Expected 3 environment reports, got %d", len(report.Environments))
+	}
+	if report.Environments[0].Report == nil || report.Environments[0].Report.TotalProducts != 2 {
+		t.Errorf("Expected staging report with 2 products, got %+v", report.Environments[0])
+	}
+	if report.Environments[2].Error == "" {
+		t.Errorf("Expected an error for the unreachable environment")
+	}
+
+	// Product 1 differs in price and currency, product 2 is missing from prod
+	expected := map[string]EnvironmentDifference{
+		"1/price":    {Values: map[string]interface{}{"staging": 109.95, "prod": 99.95}},
+		"1/currency": {Values: map[string]interface{}{"staging": "", "prod": "EUR"}},
+		"2/product":  {Values: map[string]interface{}{"staging": "present", "prod": "missing"}},
+	}
+	if len(report.Differences) != len(expected) {
+		t.Fatalf("Expected %d differences, got %+v", len(expected), report.Differences)
+	}
+	for _, diff := range report.Differences {
+		key := fmt.Sprintf("%d/%s", diff.ProductID, diff.Field)
+		want, ok := expected[key]
+		if !ok {
+			t.Errorf("Unexpected difference %+v", diff)
+			continue
+		}
+		for env, value := range want.Values {
+			if diff.Values[env] != value {
+				t.Errorf("%s: expected %s=%v, got %v", key, env, value, diff.Values[env])
+			}
+		}
+	}
+}
+
+func TestRunEnvironmentsSnapshotIgnore(t *testing.T) {
+	servers := make([]*httptest.Server, 4)
+	envs := make([]Environment, len(servers))
+	for i := range servers {
+		servers[i] = setupMockServer(t, http.StatusOK, fmt.Sprintf(`[{"id":1,"title":"Backpack","price":%d,"description":"d","rating":{"rate":3.9,"count":120}}]`, 100+i))
+		defer servers[i].Close()
+		envs[i] = Environment{Name: fmt.Sprintf("env%d", i), URL: servers[i].URL}
+	}
+
+	// Spare capacity lets appends share the backing array unless each run copies it
+	ignore := make([]string, 1, 8)
+	ignore[0] = "$[*].rating"
+	opts := runOptions{
+		Suite:           &Suite{Name: "shop", SnapshotIgnore: []string{"$[*].price"}},
+		UpdateSnapshots: true,
+		SnapshotDir:     t.TempDir(),
+		SnapshotIgnore:  ignore,
+	}
+	report := runEnvironments(envs, opts)
+
+	for _, env := range report.Environments {
+		if env.Report == nil || env.Report.Snapshot == nil || env.Report.Snapshot.Error != "" {
+			t.Errorf("Expected a snapshot for %s, got %+v", env.Name, env)
+		}
+	}
+	if len(opts.SnapshotIgnore) != 1 || opts.SnapshotIgnore[0] != "$[*].rating" {
+		t.Errorf("Expected the option ignores to be left alone, got %v", opts.SnapshotIgnore)
+	}
+}
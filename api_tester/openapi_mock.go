@@ -0,0 +1,503 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OpenAPISpec is the subset of an OpenAPI 3 document used by the mock server
+type OpenAPISpec struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+	Components struct {
+		Schemas map[string]*OpenAPISchema `json:"schemas"`
+	} `json:"components"`
+}
+
+// OpenAPIOperation describes a single method on a path
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Parameters  []OpenAPIParameter          `json:"parameters"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+}
+
+// OpenAPIParameter describes a path, query or header parameter
+type OpenAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required"`
+	Schema   *OpenAPISchema `json:"schema"`
+}
+
+// OpenAPIRequestBody describes the accepted request body of an operation
+type OpenAPIRequestBody struct {
+	Required bool                         `json:"required"`
+	Content  map[string]*OpenAPIMediaType `json:"content"`
+}
+
+// OpenAPIResponse describes one response of an operation
+type OpenAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*OpenAPIMediaType `json:"content"`
+}
+
+// OpenAPIMediaType holds the schema and examples for a content type
+type OpenAPIMediaType struct {
+	Schema   *OpenAPISchema             `json:"schema"`
+	Example  interface{}                `json:"example"`
+	Examples map[string]*OpenAPIExample `json:"examples"`
+}
+
+// OpenAPIExample is a named example value
+type OpenAPIExample struct {
+	Value interface{} `json:"value"`
+}
+
+// OpenAPISchema is the subset of JSON Schema understood by the mock server
+type OpenAPISchema struct {
+	Ref        string                    `json:"$ref"`
+	Type       string                    `json:"type"`
+	Format     string                    `json:"format"`
+	Properties map[string]*OpenAPISchema `json:"properties"`
+	Items      *OpenAPISchema            `json:"items"`
+	Required   []string                  `json:"required"`
+	Enum       []interface{}             `json:"enum"`
+	Example    interface{}               `json:"example"`
+	Default    interface{}               `json:"default"`
+	Minimum    *float64                  `json:"minimum"`
+	Maximum    *float64                  `json:"maximum"`
+}
+
+// loadOpenAPISpec reads a JSON OpenAPI document from disk
+func loadOpenAPISpec(filename string) (*OpenAPISpec, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OpenAPI file: %w", err)
+	}
+
+	var spec OpenAPISpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI file: %w", err)
+	}
+	if len(spec.Paths) == 0 {
+		return nil, fmt.Errorf("OpenAPI file %s defines no paths", filename)
+	}
+
+	return &spec, nil
+}
+
+// newOpenAPIHandler builds an HTTP handler answering every path in the spec
+func newOpenAPIHandler(spec *OpenAPISpec, validate bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		template, operations, params := matchOpenAPIPath(spec, r.URL.Path)
+		if operations == nil {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("no path matches %s", r.URL.Path))
+			return
+		}
+
+		op, ok := operations[strings.ToLower(r.Method)]
+		if !ok || op == nil {
+			writeJSONError(w, http.StatusMethodNotAllowed, fmt.Sprintf("method %s not defined for %s", r.Method, template))
+			return
+		}
+
+		// Reject requests that don't conform to the spec
+		if validate {
+			if problems := validateOpenAPIRequest(spec, op, r, params); len(problems) > 0 {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"error":    "request does not conform to the OpenAPI spec",
+					"problems": problems,
+				})
+				return
+			}
+		}
+
+		status, body, hasBody := openAPIResponseFor(spec, op)
+		if !hasBody {
+			w.WriteHeader(status)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	})
+}
+
+// writeJSONError writes an error message as a JSON object
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// matchOpenAPIPath finds the path template matching a request path and extracts its parameters
+func matchOpenAPIPath(spec *OpenAPISpec, path string) (string, map[string]*OpenAPIOperation, map[string]string) {
+	// Exact matches win over templated ones
+	if ops, ok := spec.Paths[path]; ok {
+		return path, ops, map[string]string{}
+	}
+
+	// Check templates in a stable order so results are deterministic
+	templates := make([]string, 0, len(spec.Paths))
+	for template := range spec.Paths {
+		templates = append(templates, template)
+	}
+	sort.Strings(templates)
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, template := range templates {
+		parts := strings.Split(strings.Trim(template, "/"), "/")
+		if len(parts) != len(segments) {
+			continue
+		}
+
+		params := map[string]string{}
+		matched := true
+		for i, part := range parts {
+			if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+				params[part[1:len(part)-1]] = segments[i]
+			} else if part != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return template, spec.Paths[template], params
+		}
+	}
+
+	return "", nil, nil
+}
+
+// openAPIResponseFor picks the lowest success response and produces its body
+func openAPIResponseFor(spec *OpenAPISpec, op *OpenAPIOperation) (int, interface{}, bool) {
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	// Prefer a 2xx response, then "default", then whatever is first
+	chosen := ""
+	for _, code := range codes {
+		if strings.HasPrefix(code, "2") {
+			chosen = code
+			break
+		}
+	}
+	if chosen == "" {
+		if _, ok := op.Responses["default"]; ok {
+			chosen = "default"
+		} else if len(codes) > 0 {
+			chosen = codes[0]
+		}
+	}
+
+	status, err := strconv.Atoi(chosen)
+	if err != nil {
+		status = http.StatusOK
+	}
+
+	resp := op.Responses[chosen]
+	if resp == nil {
+		return status, nil, false
+	}
+	media := jsonMediaType(resp.Content)
+	if media == nil {
+		return status, nil, false
+	}
+
+	return status, exampleForMediaType(spec, media), true
+}
+
+// jsonMediaType returns the JSON media type entry of a content map
+func jsonMediaType(content map[string]*OpenAPIMediaType) *OpenAPIMediaType {
+	if media, ok := content["application/json"]; ok {
+		return media
+	}
+	for contentType, media := range content {
+		if strings.Contains(contentType, "json") {
+			return media
+		}
+	}
+	return nil
+}
+
+// exampleForMediaType returns the explicit example of a media type or one generated from its schema
+func exampleForMediaType(spec *OpenAPISpec, media *OpenAPIMediaType) interface{} {
+	if media.Example != nil {
+		return media.Example
+	}
+
+	// Use the first named example in a stable order
+	if len(media.Examples) > 0 {
+		names := make([]string, 0, len(media.Examples))
+		for name := range media.Examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if example := media.Examples[names[0]]; example != nil {
+			return example.Value
+		}
+	}
+
+	return generateFromSchema(spec, media.Schema, 0)
+}
+
+// resolveSchema follows a local $ref to its component schema
+func resolveSchema(spec *OpenAPISpec, schema *OpenAPISchema) *OpenAPISchema {
+	for depth := 0; schema != nil && schema.Ref != "" && depth < 16; depth++ {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		schema = spec.Components.Schemas[name]
+	}
+	return schema
+}
+
+// generateFromSchema builds a sample value that satisfies a schema
+func generateFromSchema(spec *OpenAPISpec, schema *OpenAPISchema, depth int) interface{} {
+	schema = resolveSchema(spec, schema)
+	if schema == nil || depth > 8 {
+		return nil
+	}
+
+	if schema.Example != nil {
+		return schema.Example
+	}
+	if schema.Default != nil {
+		return schema.Default
+	}
+	if len(schema.Enum) > 0 {
+		return schema.Enum[0]
+	}
+
+	switch schema.Type {
+	case "object", "":
+		obj := map[string]interface{}{}
+		for name, prop := range schema.Properties {
+			obj[name] = generateFromSchema(spec, prop, depth+1)
+		}
+		return obj
+	case "array":
+		return []interface{}{generateFromSchema(spec, schema.Items, depth+1)}
+	case "integer":
+		if schema.Minimum != nil {
+			return int(*schema.Minimum)
+		}
+		return 1
+	case "number":
+		if schema.Minimum != nil {
+			return *schema.Minimum
+		}
+		return 1.5
+	case "boolean":
+		return true
+	case "string":
+		switch schema.Format {
+		case "date":
+			return "2024-01-01"
+		case "date-time":
+			return "2024-01-01T00:00:00Z"
+		case "uri", "url":
+			return "https://example.com/resource"
+		case "email":
+			return "user@example.com"
+		}
+		return "string"
+	}
+
+	return nil
+}
+
+// validateOpenAPIRequest checks parameters and body of a request against an operation
+func validateOpenAPIRequest(spec *OpenAPISpec, op *OpenAPIOperation, r *http.Request, pathParams map[string]string) []string {
+	var problems []string
+
+	for _, param := range op.Parameters {
+		var value string
+		var present bool
+		switch param.In {
+		case "path":
+			value, present = pathParams[param.Name]
+		case "query":
+			values, ok := r.URL.Query()[param.Name]
+			present = ok
+			if ok {
+				value = values[0]
+			}
+		case "header":
+			value = r.Header.Get(param.Name)
+			present = value != ""
+		default:
+			continue
+		}
+
+		if !present {
+			if param.Required || param.In == "path" {
+				problems = append(problems, fmt.Sprintf("missing required %s parameter %q", param.In, param.Name))
+			}
+			continue
+		}
+
+		for _, problem := range validateParameterValue(spec, param.Schema, value) {
+			problems = append(problems, fmt.Sprintf("%s parameter %q: %s", param.In, param.Name, problem))
+		}
+	}
+
+	if op.RequestBody != nil {
+		problems = append(problems, validateOpenAPIBody(spec, op.RequestBody, r)...)
+	}
+
+	return problems
+}
+
+// validateParameterValue checks a raw parameter string against its schema
+func validateParameterValue(spec *OpenAPISpec, schema *OpenAPISchema, value string) []string {
+	schema = resolveSchema(spec, schema)
+	if schema == nil {
+		return nil
+	}
+
+	var parsed interface{} = value
+	switch schema.Type {
+	case "integer":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return []string{fmt.Sprintf("expected integer, got %q", value)}
+		}
+		parsed = float64(n)
+	case "number":
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return []string{fmt.Sprintf("expected number, got %q", value)}
+		}
+		parsed = n
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return []string{fmt.Sprintf("expected boolean, got %q", value)}
+		}
+		parsed = b
+	}
+
+	return validateAgainstSchema(spec, schema, parsed, "")
+}
+
+// validateOpenAPIBody checks that a JSON request body matches the declared schema
+func validateOpenAPIBody(spec *OpenAPISpec, body *OpenAPIRequestBody, r *http.Request) []string {
+	if r.Body == nil || r.ContentLength == 0 {
+		if body.Required {
+			return []string{"request body is required"}
+		}
+		return nil
+	}
+
+	media := jsonMediaType(body.Content)
+	if media == nil {
+		return nil
+	}
+	if !strings.Contains(r.Header.Get("Content-Type"), "json") {
+		return []string{fmt.Sprintf("unsupported content type %q", r.Header.Get("Content-Type"))}
+	}
+
+	var value interface{}
+	if err := json.NewDecoder(r.Body).Decode(&value); err != nil {
+		return []string{fmt.Sprintf("request body is not valid JSON: %v", err)}
+	}
+
+	return validateAgainstSchema(spec, media.Schema, value, "body")
+}
+
+// validateAgainstSchema checks a decoded JSON value against a schema
+func validateAgainstSchema(spec *OpenAPISpec, schema *OpenAPISchema, value interface{}, path string) []string {
+	schema = resolveSchema(spec, schema)
+	if schema == nil {
+		return nil
+	}
+
+	label := path
+	if label == "" {
+		label = "value"
+	}
+
+	var problems []string
+	if len(schema.Enum) > 0 && !containsJSONValue(schema.Enum, value) {
+		problems = append(problems, fmt.Sprintf("%s must be one of %v", label, schema.Enum))
+	}
+
+	switch schema.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return append(problems, fmt.Sprintf("%s must be an object", label))
+		}
+		for _, name := range schema.Required {
+			if _, ok := obj[name]; !ok {
+				problems = append(problems, fmt.Sprintf("%s is missing required property %q", label, name))
+			}
+		}
+		for name, prop := range schema.Properties {
+			if v, ok := obj[name]; ok {
+				problems = append(problems, validateAgainstSchema(spec, prop, v, joinSchemaPath(path, name))...)
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return append(problems, fmt.Sprintf("%s must be an array", label))
+		}
+		for i, item := range items {
+			problems = append(problems, validateAgainstSchema(spec, schema.Items, item, fmt.Sprintf("%s[%d]", label, i))...)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			problems = append(problems, fmt.Sprintf("%s must be a string", label))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			problems = append(problems, fmt.Sprintf("%s must be a boolean", label))
+		}
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok {
+			return append(problems, fmt.Sprintf("%s must be a %s", label, schema.Type))
+		}
+		if schema.Type == "integer" && n != float64(int64(n)) {
+			problems = append(problems, fmt.Sprintf("%s must be an integer", label))
+		}
+		if schema.Minimum != nil && n < *schema.Minimum {
+			problems = append(problems, fmt.Sprintf("%s must be >= %v", label, *schema.Minimum))
+		}
+		if schema.Maximum != nil && n > *schema.Maximum {
+			problems = append(problems, fmt.Sprintf("%s must be <= %v", label, *schema.Maximum))
+		}
+	}
+
+	return problems
+}
+
+// joinSchemaPath appends a property name to a dotted location
+func joinSchemaPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// containsJSONValue reports whether value equals one of the candidates
+func containsJSONValue(candidates []interface{}, value interface{}) bool {
+	for _, candidate := range candidates {
+		if fmt.Sprint(candidate) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
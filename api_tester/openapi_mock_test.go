@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testOpenAPISpec = `{
+  "openapi": "3.0.3",
+  "paths": {
+    "/products": {
+      "get": {
+        "responses": {
+          "200": {
+            "description": "ok",
+            "content": {"application/json": {"example": [{"id": 1, "title": "Example", "price": 9.5}]}}
+          }
+        }
+      },
+      "post": {
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Product"}}}
+        },
+        "responses": {
+          "201": {
+            "description": "created",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Product"}}}
+          }
+        }
+      }
+    },
+    "/products/{id}": {
+      "get": {
+        "parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}}],
+        "responses": {
+          "200": {
+            "description": "ok",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Product"}}}
+          }
+        }
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "Product": {
+        "type": "object",
+        "required": ["title", "price"],
+        "properties": {
+          "id": {"type": "integer", "example": 7},
+          "title": {"type": "string"},
+          "price": {"type": "number", "minimum": 0}
+        }
+      }
+    }
+  }
+}`
+
+// Start an httptest server backed by the test OpenAPI spec
+func setupOpenAPIServer(t *testing.T, validate bool) *httptest.Server {
+	var spec OpenAPISpec
+	if err := json.Unmarshal([]byte(testOpenAPISpec), &spec); err != nil {
+		t.Fatalf("Failed to parse test spec: %v", err)
+	}
+	return httptest.NewServer(newOpenAPIHandler(&spec, validate))
+}
+
+func TestOpenAPIMockServesExamples(t *testing.T) {
+	server := setupOpenAPIServer(t, false)
+	defer server.Close()
+
+	// The mock output must be consumable by the regular product fetcher
+	originalURL := apiURL
+	apiURL = server.URL + "/products"
+	products, statusCode, err := fetchProducts()
+	apiURL = originalURL
+
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if statusCode != http.StatusOK {
+		t.Errorf("Expected status code 200, got %d", statusCode)
+	}
+	if len(products) != 1 || products[0].Title != "Example" {
+		t.Errorf("Expected the example product, got %+v", products)
+	}
+}
+
+func TestOpenAPIMockGeneratesFromSchema(t *testing.T) {
+	server := setupOpenAPIServer(t, false)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/products/42")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var product map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&product); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if product["id"] != float64(7) {
+		t.Errorf("Expected id from schema example, got %v", product["id"])
+	}
+	if _, ok := product["title"].(string); !ok {
+		t.Errorf("Expected generated string title, got %v", product["title"])
+	}
+}
+
+func TestOpenAPIMockValidation(t *testing.T) {
+	testCases := []struct {
+		name           string
+		validate       bool
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"Valid body", true, http.MethodPost, "/products", `{"title":"New","price":1.5}`, http.StatusCreated},
+		{"Missing required property", true, http.MethodPost, "/products", `{"title":"New"}`, http.StatusBadRequest},
+		{"Below minimum", true, http.MethodPost, "/products", `{"title":"New","price":-1}`, http.StatusBadRequest},
+		{"Missing body", true, http.MethodPost, "/products", ``, http.StatusBadRequest},
+		{"Non-integer path parameter", true, http.MethodGet, "/products/abc", ``, http.StatusBadRequest},
+		{"Validation disabled", false, http.MethodPost, "/products", `{"title":"New"}`, http.StatusCreated},
+		{"Unknown path", true, http.MethodGet, "/orders", ``, http.StatusNotFound},
+		{"Undefined method", true, http.MethodDelete, "/products", ``, http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := setupOpenAPIServer(t, tc.validate)
+			defer server.Close()
+
+			req, err := http.NewRequest(tc.method, server.URL+tc.path, strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatalf("Failed to build request: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+}
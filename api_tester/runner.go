@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// runOptions carries the command line settings that shape a test run
+type runOptions struct {
+	Suite           *Suite
+	UpdateSnapshots bool
+	SnapshotDir     string
+	SnapshotIgnore  []string
+	// SnapshotName overrides the suite name when naming the snapshot file
+	SnapshotName string
+	// InsecureTLS records that certificate verification is disabled
+	InsecureTLS bool
+	// Validation enables the optional price and text checks
+	Validation ValidationConfig
+}
+
+// runTests fetches products from url and performs every check without printing anything.
+// The error is only set when no response could be obtained at all
+func runTests(url string, opts runOptions) (TestReport, []Product, error) {
+	suite := opts.Suite
+
+	// Initialize test report
+	report := TestReport{
+		Timestamp: time.Now().Format(time.RFC3339),
+		URL:       url,
+	}
+
+	// Fetch data from API
+	var products []Product
+	var resp *http.Response
+	var body []byte
+	var err error
+	if suite != nil && suite.GraphQL != nil {
+		resp, body, err = fetchGraphQLResponse(url, suite.GraphQL)
+		if err == nil {
+			products, report.GraphQLErrors, err = parseGraphQLProducts(body, suite.GraphQL.DataPath)
+		}
+	} else {
+		resp, body, err = fetchResponseFrom(url)
+		if err == nil {
+			products, err = parseProducts(body)
+		}
+	}
+	if err != nil {
+		return report, nil, err
+	}
+
+	// Verify server response code
+	report.StatusCode = resp.StatusCode
+	report.StatusCodeValid = (resp.StatusCode == http.StatusOK)
+	report.TLS = tlsInfoFromState(resp.TLS, opts.InsecureTLS)
+
+	// Validate products and collect errors
+	validationErrors := validateProducts(products, opts.Validation)
+	report.TotalProducts = len(products)
+	report.DefectCount = len(validationErrors)
+	report.Defects = validationErrors
+	report.QualityScore = qualityScore(len(products), validationErrors)
+
+	// Check response headers and caching behaviour
+	if suite != nil && suite.Headers != nil {
+		report.HeaderChecks = runHeaderChecks(suite.Headers, url, resp.Header)
+	}
+
+	// Evaluate suite assertions against the raw response body
+	if suite != nil && len(suite.Assertions) > 0 {
+		report.Assertions = evaluateAssertions(suite.Assertions, body)
+	}
+
+	// Compare against the stored snapshot once one has been recorded
+	name := opts.SnapshotName
+	// Copied, since environments run concurrently with the same options
+	ignore := append([]string(nil), opts.SnapshotIgnore...)
+	if suite != nil {
+		if name == "" {
+			name = suite.Name
+		}
+		ignore = append(ignore, suite.SnapshotIgnore...)
+	}
+	snapshotFile := snapshotFilename(opts.SnapshotDir, name, url)
+	if _, statErr := os.Stat(snapshotFile); opts.UpdateSnapshots || statErr == nil {
+		snapshot, err := checkSnapshot(snapshotFile, body, ignore, opts.UpdateSnapshots)
+		if err != nil {
+			snapshot = &SnapshotResult{File: snapshotFile, Error: err.Error()}
+		}
+		report.Snapshot = snapshot
+	}
+
+	// Run multi-step scenarios against the same base URL
+	if suite != nil && len(suite.Scenarios) > 0 {
+		report.Scenarios = runScenarios(suite.Scenarios, url)
+	}
+
+	return report, products, nil
+}
+
+// printReport displays the results of a test run as numbered tests
+func printReport(report TestReport, suite *Suite) {
+	testNumber := 0
+	nextTest := func(name string) {
+		testNumber++
+		fmt.Printf("Test %d: %s\n", testNumber, name)
+	}
+
+	// Test 1: Verify server response code
+	nextTest("Verify server response code")
+	fmt.Printf("Status Code: %d\n", report.StatusCode)
+	if report.StatusCodeValid {
+		fmt.Println("✅ Status code is 200 OK")
+	} else {
+		fmt.Printf("❌ Expected status code 200, got %d\n", report.StatusCode)
+	}
+	if report.TLS != nil {
+		fmt.Printf("TLS: %s, %s", report.TLS.Version, report.TLS.CipherSuite)
+		if !report.TLS.Verified {
+			fmt.Print(" (certificate not verified)")
+		}
+		fmt.Println()
+	}
+	fmt.Println()
+
+	// GraphQL servers report failures in an errors array rather than the status code
+	if suite != nil && suite.GraphQL != nil {
+		nextTest("Check GraphQL errors")
+		if len(report.GraphQLErrors) == 0 {
+			fmt.Println("✅ Response contains no GraphQL errors")
+		} else {
+			fmt.Printf("❌ Response contains %d GraphQL error(s):\n", len(report.GraphQLErrors))
+			printGraphQLErrors(report.GraphQLErrors)
+		}
+		fmt.Println()
+	}
+
+	// Every header check is reported as its own test
+	for _, check := range report.HeaderChecks {
+		nextTest(check.Name)
+		printHeaderCheck(check)
+		fmt.Println()
+	}
+
+	// Display validation results
+	nextTest("Validate product attributes")
+	fmt.Printf("Total products: %d\n", report.TotalProducts)
+	fmt.Printf("Products with defects: %d\n", report.DefectCount)
+	fmt.Printf("Data-quality score: %.2f%%\n", report.QualityScore)
+	fmt.Println()
+
+	// Display the list of defects
+	if report.DefectCount > 0 {
+		fmt.Println("Defective Products:")
+		fmt.Println("-----------------")
+		printValidationErrors(report.Defects)
+	} else {
+		fmt.Println("✅ No defects found in any products")
+	}
+
+	if len(report.Assertions) > 0 {
+		fmt.Println()
+		nextTest("Evaluate response assertions")
+		printAssertionResults(report.Assertions)
+	}
+
+	if snapshot := report.Snapshot; snapshot != nil {
+		fmt.Println()
+		nextTest("Compare response with snapshot")
+		switch {
+		case snapshot.Error != "":
+			fmt.Printf("❌ Snapshot check failed: %s\n", snapshot.Error)
+		case snapshot.Updated:
+			fmt.Printf("✅ Snapshot written to %s\n", snapshot.File)
+		case snapshot.Matched:
+			fmt.Printf("✅ Response matches snapshot %s\n", snapshot.File)
+		default:
+			fmt.Printf("❌ Response deviates from snapshot %s in %d place(s):\n", snapshot.File, len(snapshot.Differences))
+			printSnapshotDifferences(snapshot.Differences)
+		}
+	}
+
+	if len(report.Scenarios) > 0 {
+		fmt.Println()
+		nextTest("Run scenarios")
+		printScenarioResults(report.Scenarios)
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Scenario is a sequence of requests where later steps can use values captured by earlier ones
+type Scenario struct {
+	Name  string         `json:"name"`
+	Steps []ScenarioStep `json:"steps"`
+}
+
+// ScenarioStep is a single request in a scenario
+type ScenarioStep struct {
+	Name    string            `json:"name"`
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    interface{}       `json:"body,omitempty"`
+	// ExpectStatus is the required status code; any 2xx is accepted when zero
+	ExpectStatus int `json:"expect_status,omitempty"`
+	// Capture maps variable names to a JSONPath into the response body or "header:<Name>"
+	Capture map[string]string `json:"capture,omitempty"`
+}
+
+// ScenarioResult records the outcome of a scenario
+type ScenarioResult struct {
+	Name   string       `json:"name"`
+	Passed bool         `json:"passed"`
+	Steps  []StepResult `json:"steps"`
+}
+
+// StepResult records the outcome of one scenario step
+type StepResult struct {
+	Name       string            `json:"name"`
+	Method     string            `json:"method"`
+	URL        string            `json:"url"`
+	StatusCode int               `json:"status_code"`
+	Passed     bool              `json:"passed"`
+	Captured   map[string]string `json:"captured,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// scenarioVariable matches {{name}} placeholders
+var scenarioVariable = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// expandVariables substitutes {{name}} placeholders, reporting any that are undefined.
+// Captured strings are inserted as is, other values as JSON
+func expandVariables(template string, vars map[string]interface{}) (string, error) {
+	var missing []string
+	expanded := scenarioVariable.ReplaceAllStringFunc(template, func(match string) string {
+		name := scenarioVariable.FindStringSubmatch(match)[1]
+		value, ok := vars[name]
+		if !ok {
+			missing = append(missing, name)
+			return match
+		}
+		return variableText(value)
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("undefined variable(s): %s", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// variableText formats a captured value for use inside text
+func variableText(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return formatJSONValue(value)
+}
+
+// expandBodyVariables substitutes placeholders in the keys and values of a decoded JSON
+// body. A value that is a single placeholder, such as "{{id}}", takes the captured value
+// with its type, so a captured number stays a number
+func expandBodyVariables(value interface{}, vars map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if match := scenarioVariable.FindStringSubmatchIndex(v); match != nil && match[0] == 0 && match[1] == len(v) {
+			name := v[match[2]:match[3]]
+			captured, ok := vars[name]
+			if !ok {
+				return nil, fmt.Errorf("undefined variable(s): %s", name)
+			}
+			return captured, nil
+		}
+		return expandVariables(v, vars)
+	case map[string]interface{}:
+		expanded := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			expandedKey, err := expandVariables(key, vars)
+			if err != nil {
+				return nil, err
+			}
+			if expanded[expandedKey], err = expandBodyVariables(item, vars); err != nil {
+				return nil, err
+			}
+		}
+		return expanded, nil
+	case []interface{}:
+		expanded := make([]interface{}, len(v))
+		for i, item := range v {
+			var err error
+			if expanded[i], err = expandBodyVariables(item, vars); err != nil {
+				return nil, err
+			}
+		}
+		return expanded, nil
+	default:
+		return v, nil
+	}
+}
+
+// runScenarios runs every scenario, resolving relative step URLs against baseURL
+func runScenarios(scenarios []Scenario, baseURL string) []ScenarioResult {
+	results := make([]ScenarioResult, 0, len(scenarios))
+	for _, scenario := range scenarios {
+		results = append(results, runScenario(scenario, baseURL))
+	}
+	return results
+}
+
+// runScenario runs the steps of a scenario in order, stopping at the first failure
+func runScenario(scenario Scenario, baseURL string) ScenarioResult {
+	result := ScenarioResult{Name: scenario.Name, Passed: true}
+	vars := map[string]interface{}{}
+
+	for i, step := range scenario.Steps {
+		stepResult := runScenarioStep(step, baseURL, vars)
+		if stepResult.Name == "" {
+			stepResult.Name = fmt.Sprintf("step %d", i+1)
+		}
+		result.Steps = append(result.Steps, stepResult)
+
+		if !stepResult.Passed {
+			result.Passed = false
+			break
+		}
+	}
+
+	return result
+}
+
+// runScenarioStep sends one templated request and captures variables from its response
+func runScenarioStep(step ScenarioStep, baseURL string, vars map[string]interface{}) StepResult {
+	method := strings.ToUpper(step.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	result := StepResult{Name: step.Name, Method: method}
+
+	// Expand variables in the URL, headers and body
+	rawURL, err := expandVariables(step.URL, vars)
+	if err != nil {
+		result.Error = fmt.Sprintf("url: %v", err)
+		return result
+	}
+	target, err := resolveStepURL(baseURL, rawURL)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.URL = target
+
+	var body io.Reader
+	if step.Body != nil {
+		encoded, err := stepBody(step.Body, vars)
+		if err != nil {
+			result.Error = fmt.Sprintf("body: %v", err)
+			return result
+		}
+		body = strings.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to build request: %v", err)
+		return result
+	}
+	if step.Body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range step.Headers {
+		expanded, err := expandVariables(value, vars)
+		if err != nil {
+			result.Error = fmt.Sprintf("header %s: %v", name, err)
+			return result
+		}
+		req.Header.Set(name, expanded)
+	}
+
+	// Make HTTP request
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to make request: %v", err)
+		return result
+	}
+	defer resp.Body.Close()
+	result.StatusCode = resp.StatusCode
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to read response body: %v", err)
+		return result
+	}
+
+	if step.ExpectStatus != 0 && resp.StatusCode != step.ExpectStatus {
+		result.Error = fmt.Sprintf("expected status code %d, got %d", step.ExpectStatus, resp.StatusCode)
+		return result
+	}
+	if step.ExpectStatus == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		result.Error = fmt.Sprintf("expected a 2xx status code, got %d", resp.StatusCode)
+		return result
+	}
+
+	// Capture values for later steps
+	if len(step.Capture) > 0 {
+		result.Captured = map[string]string{}
+		for _, name := range sortedStringKeys(step.Capture) {
+			value, err := captureValue(step.Capture[name], resp.Header, respBody)
+			if err != nil {
+				result.Error = fmt.Sprintf("capture %s: %v", name, err)
+				return result
+			}
+			vars[name] = value
+			result.Captured[name] = variableText(value)
+		}
+	}
+
+	result.Passed = true
+	return result
+}
+
+// resolveStepURL resolves a possibly relative step URL against the base URL
+func resolveStepURL(baseURL, rawURL string) (string, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL %q: %w", baseURL, err)
+	}
+	ref, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid step URL %q: %w", rawURL, err)
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// stepBody expands the variables in a step body and encodes it; strings are sent verbatim,
+// anything else as JSON
+func stepBody(body interface{}, vars map[string]interface{}) (string, error) {
+	if s, ok := body.(string); ok {
+		return expandVariables(s, vars)
+	}
+	expanded, err := expandBodyVariables(body, vars)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := json.Marshal(expanded)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode body: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// captureValue extracts a value from a response header ("header:Name") or the JSON body (a
+// JSONPath), keeping the JSON type of body values
+func captureValue(source string, header http.Header, body []byte) (interface{}, error) {
+	if strings.HasPrefix(source, "header:") {
+		name := strings.TrimSpace(strings.TrimPrefix(source, "header:"))
+		value := header.Get(name)
+		if value == "" {
+			return "", fmt.Errorf("response has no %s header", name)
+		}
+		return value, nil
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return "", fmt.Errorf("response body is not valid JSON: %w", err)
+	}
+	nodes, err := evaluateJSONPath(source, doc)
+	if err != nil {
+		return "", err
+	}
+	if len(nodes) == 0 {
+		return "", fmt.Errorf("%s matched nothing", source)
+	}
+	return nodes[0].Value, nil
+}
+
+// sortedStringKeys returns the keys of a string map in a stable order
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// printScenarioResults displays each scenario with its steps
+func printScenarioResults(results []ScenarioResult) {
+	for _, scenario := range results {
+		if scenario.Passed {
+			fmt.Printf("✅ %s\n", scenario.Name)
+		} else {
+			fmt.Printf("❌ %s\n", scenario.Name)
+		}
+		for _, step := range scenario.Steps {
+			status := "✅"
+			if !step.Passed {
+				status = "❌"
+			}
+			fmt.Printf("   %s %s %s %s (%d)\n", status, step.Name, step.Method, step.URL, step.StatusCode)
+			for _, name := range sortedStringKeys(step.Captured) {
+				fmt.Printf("      %s = %s\n", name, step.Captured[name])
+			}
+			if step.Error != "" {
+				fmt.Printf("      %s\n", step.Error)
+			}
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Start a tiny product store supporting create, read and delete
+func setupProductStore(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	products := map[string]map[string]interface{}{}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		id := strings.TrimPrefix(r.URL.Path, "/products/")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/products":
+			var product map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&product)
+			product["id"] = 21
+			products["21"] = product
+			w.Header().Set("Location", "/products/21")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(product)
+		case r.Method == http.MethodGet && products[id] != nil:
+			if r.Header.Get("X-Trace") != "trace-21" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(products[id])
+		case r.Method == http.MethodDelete && products[id] != nil:
+			delete(products, id)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestRunScenario(t *testing.T) {
+	server := setupProductStore(t)
+	defer server.Close()
+
+	scenario := Scenario{
+		Name: "Create, read and delete a product",
+		Steps: []ScenarioStep{
+			{
+				Name:         "create",
+				Method:       "POST",
+				URL:          "/products",
+				Body:         map[string]interface{}{"title": "Scenario Product", "price": 12.5},
+				ExpectStatus: http.StatusCreated,
+				Capture:      map[string]string{"id": "$.id", "location": "header:Location"},
+			},
+			{
+				Name:    "read",
+				URL:     "{{location}}",
+				Headers: map[string]string{"X-Trace": "trace-{{id}}"},
+				Capture: map[string]string{"title": "$.title"},
+			},
+			{
+				Name:         "delete",
+				Method:       "DELETE",
+				URL:          "/products/{{id}}",
+				ExpectStatus: http.StatusNoContent,
+			},
+			{
+				Name:         "read after delete",
+				URL:          "/products/{{id}}",
+				ExpectStatus: http.StatusNotFound,
+			},
+		},
+	}
+
+	result := runScenario(scenario, server.URL+"/products")
+	if !result.Passed {
+		t.Fatalf("Expected scenario to pass, got %+v", result)
+	}
+	if len(result.Steps) != 4 {
+		t.Fatalf("Expected 4 step results, got %d", len(result.Steps))
+	}
+	if result.Steps[0].Captured["id"] != "21" || result.Steps[1].Captured["title"] != "Scenario Product" {
+		t.Errorf("Unexpected captured values %+v %+v", result.Steps[0].Captured, result.Steps[1].Captured)
+	}
+	if result.Steps[2].URL != server.URL+"/products/21" {
+		t.Errorf("Expected templated URL, got %s", result.Steps[2].URL)
+	}
+}
+
+func TestRunScenarioStopsAtFailure(t *testing.T) {
+	server := setupProductStore(t)
+	defer server.Close()
+
+	scenario := Scenario{
+		Name: "Read a missing product",
+		Steps: []ScenarioStep{
+			{Name: "read", URL: "/products/999"},
+			{Name: "never runs", URL: "/products/{{id}}"},
+		},
+	}
+
+	result := runScenario(scenario, server.URL)
+	if result.Passed {
+		t.Errorf("Expected scenario to fail")
+	}
+	if len(result.Steps) != 1 || result.Steps[0].StatusCode != http.StatusNotFound {
+		t.Errorf("Expected a single failed step, got %+v", result.Steps)
+	}
+}
+
+func TestExpandVariables(t *testing.T) {
+	vars := map[string]interface{}{"id": float64(7)}
+	if got, err := expandVariables("/products/{{ id }}", vars); err != nil || got != "/products/7" {
+		t.Errorf("Expected /products/7, got %q (%v)", got, err)
+	}
+	if _, err := expandVariables("/products/{{missing}}", vars); err == nil {
+		t.Errorf("Expected error for undefined variable, got nil")
+	}
+}
+
+func TestStepBody(t *testing.T) {
+	vars := map[string]interface{}{"id": float64(7), "name": `Bob "B" \ Jr`, "tags": []interface{}{"a"}}
+
+	testCases := []struct {
+		name        string
+		body        interface{}
+		expected    string
+		expectError bool
+	}{
+		{
+			"Whole-value placeholders keep their type",
+			map[string]interface{}{"id": "{{id}}", "tags": "{{ tags }}"},
+			`{"id":7,"tags":["a"]}`, false,
+		},
+		{
+			"Quotes and backslashes are escaped",
+			map[string]interface{}{"customer": "{{name}}", "note": "for {{name}} #{{id}}"},
+			`{"customer":"Bob \"B\" \\ Jr","note":"for Bob \"B\" \\ Jr #7"}`, false,
+		},
+		{
+			"Nested arrays",
+			[]interface{}{map[string]interface{}{"id": "{{id}}"}},
+			`[{"id":7}]`, false,
+		},
+		{
+			"Raw string bodies are expanded as text",
+			"id={{id}}",
+			"id=7", false,
+		},
+		{"Undefined whole-value placeholder", map[string]interface{}{"id": "{{missing}}"}, "", true},
+		{"Undefined placeholder inside text", []interface{}{"x {{missing}}"}, "", true},
+		{"Unencodable body", map[string]interface{}{"f": func() {}}, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := stepBody(tc.body, vars)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error, got body %s", got)
+				}
+				return
+			}
+			if err != nil || got != tc.expected {
+				t.Errorf("Expected %s, got %s (%v)", tc.expected, got, err)
+			}
+		})
+	}
+}
+
+func TestRunScenarioBodyErrors(t *testing.T) {
+	server := setupProductStore(t)
+	defer server.Close()
+
+	step := ScenarioStep{Name: "create", Method: "POST", URL: "/products", Body: map[string]interface{}{"id": "{{missing}}"}}
+	result := runScenarioStep(step, server.URL, map[string]interface{}{})
+	if result.Passed || !strings.Contains(result.Error, "undefined variable(s): missing") {
+		t.Errorf("Expected the step to fail on the undefined variable, got %+v", result)
+	}
+	if result.StatusCode != 0 {
+		t.Errorf("Expected no request to be sent, got status %d", result.StatusCode)
+	}
+}
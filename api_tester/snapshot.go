@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SnapshotResult records the outcome of comparing a response against its snapshot
+type SnapshotResult struct {
+	File        string               `json:"file"`
+	Updated     bool                 `json:"updated"`
+	Matched     bool                 `json:"matched"`
+	Differences []SnapshotDifference `json:"differences,omitempty"`
+	Error       string               `json:"error,omitempty"`
+}
+
+// SnapshotDifference is a single deviation between the snapshot and the live response
+type SnapshotDifference struct {
+	Path     string      `json:"path"`
+	Kind     string      `json:"kind"` // "added", "removed" or "changed"
+	Expected interface{} `json:"expected,omitempty"`
+	Actual   interface{} `json:"actual,omitempty"`
+}
+
+// snapshotNameCleaner replaces characters that are unsafe in file names
+var snapshotNameCleaner = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// snapshotFilename derives the snapshot file for a suite name or, failing that, the URL
+func snapshotFilename(dir, name, rawURL string) string {
+	if name == "" {
+		if u, err := url.Parse(rawURL); err == nil {
+			name = u.Host + u.Path
+		} else {
+			name = rawURL
+		}
+	}
+	name = strings.Trim(snapshotNameCleaner.ReplaceAllString(name, "_"), "_")
+	if name == "" {
+		name = "snapshot"
+	}
+	return filepath.Join(dir, name+".json")
+}
+
+// normalizeSnapshot decodes a response body and removes the volatile paths
+func normalizeSnapshot(body []byte, ignore []string) (interface{}, error) {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("response body is not valid JSON: %w", err)
+	}
+
+	ignored := map[string]bool{}
+	for _, expr := range ignore {
+		nodes, err := evaluateJSONPath(expr, doc)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodes {
+			ignored[node.Path] = true
+		}
+	}
+	if ignored["$"] {
+		return nil, nil
+	}
+
+	return removeIgnoredPaths(doc, "$", ignored), nil
+}
+
+// removeIgnoredPaths copies doc without the nodes whose locations are in ignored
+func removeIgnoredPaths(value interface{}, path string, ignored map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			childPath := path + "." + key
+			if !ignored[childPath] {
+				out[key] = removeIgnoredPaths(child, childPath, ignored)
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, 0, len(v))
+		for i, child := range v {
+			childPath := fmt.Sprintf("%s[%d]", path, i)
+			if !ignored[childPath] {
+				out = append(out, removeIgnoredPaths(child, childPath, ignored))
+			}
+		}
+		return out
+	}
+	return value
+}
+
+// checkSnapshot compares a response body with the stored snapshot, or rewrites it when update is set
+func checkSnapshot(filename string, body []byte, ignore []string, update bool) (*SnapshotResult, error) {
+	actual, err := normalizeSnapshot(body, ignore)
+	if err != nil {
+		return nil, err
+	}
+	result := &SnapshotResult{File: filename}
+
+	if update {
+		encoded, err := json.MarshalIndent(actual, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode snapshot: %w", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+		}
+		if err := os.WriteFile(filename, append(encoded, '\n'), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write snapshot: %w", err)
+		}
+		result.Updated = true
+		result.Matched = true
+		return result, nil
+	}
+
+	stored, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot (run with -update-snapshots to create it): %w", err)
+	}
+	var expected interface{}
+	if err := json.Unmarshal(stored, &expected); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", filename, err)
+	}
+
+	result.Differences = diffJSON("$", expected, actual)
+	result.Matched = len(result.Differences) == 0
+	return result, nil
+}
+
+// diffJSON lists the differences between two decoded JSON documents
+func diffJSON(path string, expected, actual interface{}) []SnapshotDifference {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			break
+		}
+		var diffs []SnapshotDifference
+		for _, key := range sortedKeys(e) {
+			childPath := path + "." + key
+			if av, ok := a[key]; ok {
+				diffs = append(diffs, diffJSON(childPath, e[key], av)...)
+			} else {
+				diffs = append(diffs, SnapshotDifference{Path: childPath, Kind: "removed", Expected: e[key]})
+			}
+		}
+		for _, key := range sortedKeys(a) {
+			if _, ok := e[key]; !ok {
+				diffs = append(diffs, SnapshotDifference{Path: path + "." + key, Kind: "added", Actual: a[key]})
+			}
+		}
+		return diffs
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok {
+			break
+		}
+		var diffs []SnapshotDifference
+		for i := 0; i < len(e) || i < len(a); i++ {
+			childPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(a):
+				diffs = append(diffs, SnapshotDifference{Path: childPath, Kind: "removed", Expected: e[i]})
+			case i >= len(e):
+				diffs = append(diffs, SnapshotDifference{Path: childPath, Kind: "added", Actual: a[i]})
+			default:
+				diffs = append(diffs, diffJSON(childPath, e[i], a[i])...)
+			}
+		}
+		return diffs
+	default:
+		if formatJSONValue(expected) == formatJSONValue(actual) {
+			return nil
+		}
+	}
+
+	return []SnapshotDifference{{Path: path, Kind: "changed", Expected: expected, Actual: actual}}
+}
+
+// printSnapshotDifferences displays a snapshot diff, one line per difference
+func printSnapshotDifferences(diffs []SnapshotDifference) {
+	for _, diff := range diffs {
+		switch diff.Kind {
+		case "added":
+			fmt.Printf("+ %s: %s\n", diff.Path, formatJSONValue(diff.Actual))
+		case "removed":
+			fmt.Printf("- %s: %s\n", diff.Path, formatJSONValue(diff.Expected))
+		default:
+			fmt.Printf("~ %s: %s -> %s\n", diff.Path, formatJSONValue(diff.Expected), formatJSONValue(diff.Actual))
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckSnapshot(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "snapshot-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	filename := snapshotFilename(tempDir, "", "https://fakestoreapi.com/products")
+	if filepath.Base(filename) != "fakestoreapi_com_products.json" {
+		t.Errorf("Unexpected snapshot file name %s", filepath.Base(filename))
+	}
+
+	original := []byte(`[{"id":1,"title":"A","price":10,"rating":{"rate":4,"count":5}}]`)
+	ignore := []string{"$[*].rating.count"}
+
+	// Recording a snapshot always matches
+	result, err := checkSnapshot(filename, original, ignore, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.Updated || !result.Matched {
+		t.Errorf("Expected updated and matched snapshot, got %+v", result)
+	}
+
+	testCases := []struct {
+		name          string
+		body          string
+		expectedDiffs []SnapshotDifference
+	}{
+		{
+			name: "Unchanged",
+			body: `[{"id":1,"title":"A","price":10,"rating":{"rate":4,"count":5}}]`,
+		},
+		{
+			name: "Only ignored field changed",
+			body: `[{"id":1,"title":"A","price":10,"rating":{"rate":4,"count":999}}]`,
+		},
+		{
+			name: "Price changed and field added",
+			body: `[{"id":1,"title":"A","price":12.5,"rating":{"rate":4,"count":5},"stock":3}]`,
+			expectedDiffs: []SnapshotDifference{
+				{Path: "$[0].price", Kind: "changed"},
+				{Path: "$[0].stock", Kind: "added"},
+			},
+		},
+		{
+			name: "Product removed",
+			body: `[]`,
+			expectedDiffs: []SnapshotDifference{
+				{Path: "$[0]", Kind: "removed"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := checkSnapshot(filename, []byte(tc.body), ignore, false)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if result.Matched != (len(tc.expectedDiffs) == 0) {
+				t.Errorf("Expected matched=%v, got %v", len(tc.expectedDiffs) == 0, result.Matched)
+			}
+			if len(result.Differences) != len(tc.expectedDiffs) {
+				t.Fatalf("Expected %d differences, got %+v", len(tc.expectedDiffs), result.Differences)
+			}
+			for i, diff := range result.Differences {
+				if diff.Path != tc.expectedDiffs[i].Path || diff.Kind != tc.expectedDiffs[i].Kind {
+					t.Errorf("Expected %s %s, got %s %s", tc.expectedDiffs[i].Kind, tc.expectedDiffs[i].Path, diff.Kind, diff.Path)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckSnapshotMissing(t *testing.T) {
+	_, err := checkSnapshot(filepath.Join(os.TempDir(), "does-not-exist", "x.json"), []byte(`[]`), nil, false)
+	if err == nil {
+		t.Errorf("Expected error for missing snapshot, got nil")
+	}
+}
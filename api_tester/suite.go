@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Suite describes what to test, loaded from a JSON file with the -suite flag
+type Suite struct {
+	Name    string        `json:"name"`
+	URL     string        `json:"url"`
+	GraphQL *GraphQLQuery `json:"graphql,omitempty"`
+	// Assertions are JSONPath expressions evaluated against the raw response body
+	Assertions []string `json:"assertions,omitempty"`
+	// SnapshotIgnore lists JSONPaths of volatile fields left out of snapshots
+	SnapshotIgnore []string `json:"snapshot_ignore,omitempty"`
+	// Environments maps environment names to base URLs compared against each other
+	Environments map[string]string `json:"environments,omitempty"`
+	// Scenarios are multi-step request flows; relative step URLs resolve against the suite URL
+	Scenarios []Scenario `json:"scenarios,omitempty"`
+	// Headers describes the response header and caching checks
+	Headers *HeaderChecks `json:"headers,omitempty"`
+	// Validation configures the product rules, such as per-currency price precision
+	Validation *ValidationConfig `json:"validation,omitempty"`
+}
+
+// loadSuite reads a suite definition from disk
+func loadSuite(filename string) (*Suite, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suite file: %w", err)
+	}
+
+	var suite Suite
+	if err := json.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse suite file: %w", err)
+	}
+
+	if suite.GraphQL != nil && suite.GraphQL.Query == "" {
+		return nil, fmt.Errorf("suite %s: graphql.query must not be empty", filename)
+	}
+
+	for _, scenario := range suite.Scenarios {
+		if len(scenario.Steps) == 0 {
+			return nil, fmt.Errorf("suite %s: scenario %q has no steps", filename, scenario.Name)
+		}
+	}
+
+	if suite.Headers != nil && suite.Headers.CORS != nil && suite.Headers.CORS.Origin == "" {
+		return nil, fmt.Errorf("suite %s: headers.cors.origin must not be empty", filename)
+	}
+
+	if suite.Validation != nil && suite.Validation.Prices != nil {
+		for currency, digits := range suite.Validation.Prices.Precision {
+			if digits < 0 {
+				return nil, fmt.Errorf("suite %s: precision for %s must not be negative", filename, currency)
+			}
+		}
+	}
+
+	return &suite, nil
+}
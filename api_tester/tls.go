@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// transportOptions configures how the tester connects to the API
+type transportOptions struct {
+	// Proxy overrides the HTTP_PROXY/HTTPS_PROXY environment variables
+	Proxy string
+	// CACert is a PEM bundle trusted in addition to the system roots
+	CACert string
+	// Cert and Key are the PEM client certificate and key used for mutual TLS
+	Cert     string
+	Key      string
+	Insecure bool
+}
+
+// TLSInfo describes the TLS session negotiated with the server
+type TLSInfo struct {
+	Version     string `json:"version"`
+	CipherSuite string `json:"cipher_suite"`
+	ServerName  string `json:"server_name,omitempty"`
+	Protocol    string `json:"protocol,omitempty"`
+	PeerSubject string `json:"peer_subject,omitempty"`
+	PeerExpires string `json:"peer_expires,omitempty"`
+	// Verified is false when certificate verification was skipped with -insecure
+	Verified bool `json:"verified"`
+}
+
+// buildTransport creates the HTTP transport for the given proxy and TLS settings
+func buildTransport(opts transportOptions) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if opts.Proxy != "" {
+		proxyURL, err := url.Parse(opts.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", opts.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: opts.Insecure}
+
+	if opts.CACert != "" {
+		pem, err := os.ReadFile(opts.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", opts.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if opts.Cert != "" || opts.Key != "" {
+		if opts.Cert == "" || opts.Key == "" {
+			return nil, fmt.Errorf("client certificate and key must be given together")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.Cert, opts.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// tlsInfoFromState summarizes a connection state; it returns nil for plain HTTP
+func tlsInfoFromState(state *tls.ConnectionState, insecure bool) *TLSInfo {
+	if state == nil {
+		return nil
+	}
+
+	info := &TLSInfo{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ServerName:  state.ServerName,
+		Protocol:    state.NegotiatedProtocol,
+		Verified:    !insecure,
+	}
+	if len(state.PeerCertificates) > 0 {
+		leaf := state.PeerCertificates[0]
+		info.PeerSubject = leaf.Subject.String()
+		info.PeerExpires = leaf.NotAfter.Format(time.RFC3339)
+	}
+	return info
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildTransport(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "tls-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Trust the test server's self-signed certificate through a CA bundle
+	caFile := filepath.Join(tempDir, "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0644); err != nil {
+		t.Fatalf("Failed to write CA bundle: %v", err)
+	}
+	emptyFile := filepath.Join(tempDir, "empty.pem")
+	os.WriteFile(emptyFile, []byte("not a certificate"), 0644)
+
+	testCases := []struct {
+		name           string
+		opts           transportOptions
+		expectBuildErr bool
+		expectFetchErr bool
+	}{
+		{"System roots reject self-signed certificate", transportOptions{}, false, true},
+		{"Custom CA bundle", transportOptions{CACert: caFile}, false, false},
+		{"Insecure skip verify", transportOptions{Insecure: true}, false, false},
+		{"CA bundle without certificates", transportOptions{CACert: emptyFile}, true, false},
+		{"Missing CA bundle", transportOptions{CACert: filepath.Join(tempDir, "missing.pem")}, true, false},
+		{"Certificate without key", transportOptions{Cert: caFile}, true, false},
+		{"Invalid proxy", transportOptions{Proxy: "::not a url"}, true, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			transport, err := buildTransport(tc.opts)
+			if tc.expectBuildErr {
+				if err == nil {
+					t.Errorf("Expected transport error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected transport error: %v", err)
+			}
+
+			originalTransport := httpClient.Transport
+			httpClient.Transport = transport
+			defer func() { httpClient.Transport = originalTransport }()
+
+			report, _, err := runTests(server.URL, runOptions{InsecureTLS: tc.opts.Insecure})
+			if tc.expectFetchErr {
+				if err == nil {
+					t.Errorf("Expected fetch error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected fetch error: %v", err)
+			}
+			if report.TLS == nil || report.TLS.Version == "" || report.TLS.CipherSuite == "" {
+				t.Fatalf("Expected TLS details in report, got %+v", report.TLS)
+			}
+			if report.TLS.Verified == tc.opts.Insecure {
+				t.Errorf("Expected verified=%v, got %v", !tc.opts.Insecure, report.TLS.Verified)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ANSI escape sequences used by the defect browser
+const (
+	ansiClear  = "\x1b[2J\x1b[H"
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+	ansiReset  = "\x1b[0m"
+)
+
+// browserPageSize is the number of defects listed per screen
+const browserPageSize = 15
+
+// defectFilter narrows the defects shown in the browser; empty fields match everything
+type defectFilter struct {
+	Field    string
+	Severity string
+	Category string
+}
+
+// defectBrowser is an interactive terminal view over the defects of a run
+type defectBrowser struct {
+	defects  []ValidationError
+	products []Product
+	filter   defectFilter
+	page     int
+	message  string
+	in       *bufio.Scanner
+	out      io.Writer
+}
+
+// runDefectBrowser lets the user list, filter and inspect defects until they quit
+func runDefectBrowser(in io.Reader, out io.Writer, defects []ValidationError, products []Product) error {
+	b := &defectBrowser{
+		defects:  defects,
+		products: products,
+		in:       bufio.NewScanner(in),
+		out:      out,
+	}
+
+	for {
+		b.renderList()
+		command, ok := b.readLine()
+		if !ok {
+			return b.in.Err()
+		}
+		if quit := b.handle(command); quit {
+			fmt.Fprint(b.out, ansiClear)
+			return nil
+		}
+	}
+}
+
+// readLine reads the next command, reporting false at end of input
+func (b *defectBrowser) readLine() (string, bool) {
+	if !b.in.Scan() {
+		return "", false
+	}
+	return strings.TrimSpace(b.in.Text()), true
+}
+
+// visible returns the defects matching the current filter
+func (b *defectBrowser) visible() []ValidationError {
+	var matched []ValidationError
+	for _, defect := range b.defects {
+		if b.filter.Field != "" && !strings.EqualFold(defect.Field, b.filter.Field) {
+			continue
+		}
+		if b.filter.Severity != "" && !strings.EqualFold(defect.Severity, b.filter.Severity) {
+			continue
+		}
+		if b.filter.Category != "" && !strings.EqualFold(b.product(defect).Category, b.filter.Category) {
+			continue
+		}
+		matched = append(matched, defect)
+	}
+	return matched
+}
+
+// handle applies a command and reports whether the browser should close
+func (b *defectBrowser) handle(command string) bool {
+	b.message = ""
+	name, arg := command, ""
+	if i := strings.IndexByte(command, ' '); i >= 0 {
+		name, arg = command[:i], strings.TrimSpace(command[i+1:])
+	}
+
+	switch strings.ToLower(name) {
+	case "", "r":
+		// Redraw
+	case "q", "quit":
+		return true
+	case "n":
+		if (b.page+1)*browserPageSize < len(b.visible()) {
+			b.page++
+		}
+	case "p":
+		if b.page > 0 {
+			b.page--
+		}
+	case "field", "f":
+		b.filter.Field = arg
+		b.page = 0
+	case "severity", "s":
+		b.filter.Severity = arg
+		b.page = 0
+	case "category", "c":
+		b.filter.Category = arg
+		b.page = 0
+	case "clear":
+		b.filter = defectFilter{}
+		b.page = 0
+	case "h", "help", "?":
+		b.message = "Commands: <number> open product, n/p next/previous page, f <field>, s <severity>, c <category>, clear, q quit"
+	default:
+		index, err := strconv.Atoi(name)
+		visible := b.visible()
+		if err != nil || index < 1 || index > len(visible) {
+			b.message = fmt.Sprintf("Unknown command %q (type h for help)", command)
+			return false
+		}
+		return b.showProduct(visible[index-1])
+	}
+	return false
+}
+
+// renderList draws the filtered, paginated list of defects
+func (b *defectBrowser) renderList() {
+	visible := b.visible()
+	pages := (len(visible) + browserPageSize - 1) / browserPageSize
+	if pages == 0 {
+		pages = 1
+	}
+	if b.page >= pages {
+		b.page = pages - 1
+	}
+
+	fmt.Fprint(b.out, ansiClear)
+	fmt.Fprintf(b.out, "%sAPI Tester - Defect Browser%s\n", ansiBold, ansiReset)
+	fmt.Fprintf(b.out, "Filter: field=%s severity=%s category=%s  (%d of %d defects)\n\n",
+		filterLabel(b.filter.Field), filterLabel(b.filter.Severity), filterLabel(b.filter.Category),
+		len(visible), len(b.defects))
+
+	start := b.page * browserPageSize
+	end := start + browserPageSize
+	if end > len(visible) {
+		end = len(visible)
+	}
+	for i := start; i < end; i++ {
+		defect := visible[i]
+		title := defect.Title
+		if title == "" {
+			title = "<empty>"
+		}
+		fmt.Fprintf(b.out, "%3d. %s%-8s%s #%d %s\n", i+1, severityColor(defect.Severity), defect.Severity, ansiReset, defect.ProductID, title)
+		fmt.Fprintf(b.out, "     %s%s: %s (%v)%s\n", ansiDim, defect.Field, defect.Message, defect.ActualValue, ansiReset)
+	}
+	if len(visible) == 0 {
+		fmt.Fprintln(b.out, "No defects match the filter")
+	}
+
+	fmt.Fprintf(b.out, "\nPage %d/%d\n", b.page+1, pages)
+	if b.message != "" {
+		fmt.Fprintln(b.out, b.message)
+	}
+	fmt.Fprint(b.out, "[number] open  [n/p] page  [f/s/c <value>] filter  [clear]  [h] help  [q] quit > ")
+}
+
+// product returns the product behind a defect, or a zero product if it isn't known
+func (b *defectBrowser) product(defect ValidationError) Product {
+	if defect.Index < 0 || defect.Index >= len(b.products) {
+		return Product{}
+	}
+	return b.products[defect.Index]
+}
+
+// showProduct displays the full JSON of the product behind a defect, as received
+func (b *defectBrowser) showProduct(defect ValidationError) bool {
+	fmt.Fprint(b.out, ansiClear)
+	fmt.Fprintf(b.out, "%sProduct #%d%s - %s%s%s: %s\n\n", ansiBold, defect.ProductID, ansiReset,
+		severityColor(defect.Severity), defect.Severity, ansiReset, defect.Message)
+
+	var indented bytes.Buffer
+	if raw := b.product(defect).Raw; len(raw) > 0 && json.Indent(&indented, raw, "", "  ") == nil {
+		fmt.Fprintln(b.out, indented.String())
+	} else {
+		fmt.Fprintln(b.out, "Product data is not available")
+	}
+
+	fmt.Fprint(b.out, "\nPress Enter to return, q to quit > ")
+	command, ok := b.readLine()
+	return !ok || strings.EqualFold(command, "q")
+}
+
+// filterLabel renders an empty filter value as "any"
+func filterLabel(value string) string {
+	if value == "" {
+		return "any"
+	}
+	return value
+}
+
+// severityColor picks the ANSI color for a severity
+func severityColor(severity string) string {
+	switch severity {
+	case SeverityCritical:
+		return ansiRed
+	case SeverityMajor:
+		return ansiYellow
+	}
+	return ansiCyan
+}
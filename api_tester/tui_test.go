@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDefectBrowser(t *testing.T) {
+	// Both products share an ID, and the second has a field the Product struct doesn't model
+	products, err := parseProducts([]byte(`[
+		{"id": 1, "title": "A very long product title that must not be truncated", "category": "electronics"},
+		{"id": 1, "title": "Shirt", "category": "clothing", "description": "cotton shirt", "sku": "SH-1"}
+	]`))
+	if err != nil {
+		t.Fatalf("Failed to parse products: %v", err)
+	}
+	defects := []ValidationError{
+		{ProductID: 1, Title: products[0].Title, Field: "price", Message: "Price is negative", Severity: SeverityCritical, ActualValue: -1, Index: 0},
+		{ProductID: 1, Title: "Shirt", Field: "description", Message: "Description is empty", Severity: SeverityMinor, ActualValue: "", Index: 1},
+	}
+
+	testCases := []struct {
+		name        string
+		input       string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:     "Lists all defects with full titles",
+			input:    "q\n",
+			contains: []string{"(2 of 2 defects)", "A very long product title that must not be truncated", "Price is negative"},
+		},
+		{
+			name:        "Filters by severity",
+			input:       "s critical\nq\n",
+			contains:    []string{"severity=critical", "(1 of 2 defects)"},
+			notContains: []string{"#1 Shirt"},
+		},
+		{
+			name:     "Filters by category",
+			input:    "c clothing\nq\n",
+			contains: []string{"category=clothing", "#1 Shirt"},
+		},
+		{
+			name:     "Filters by field with no match",
+			input:    "f rating\nq\n",
+			contains: []string{"No defects match the filter"},
+		},
+		{
+			name:     "Opens the product JSON as received",
+			input:    "2\n\nq\n",
+			contains: []string{"Product #1", `"description": "cotton shirt"`, `"sku": "SH-1"`},
+		},
+		{
+			name:     "Reports unknown commands",
+			input:    "9\n",
+			contains: []string{`Unknown command "9"`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := runDefectBrowser(strings.NewReader(tc.input), &out, defects, products); err != nil {
+				t.Fatalf("Browser failed: %v", err)
+			}
+			for _, expected := range tc.contains {
+				if !strings.Contains(out.String(), expected) {
+					t.Errorf("Expected output to contain %q, got:\n%s", expected, out.String())
+				}
+			}
+			// Only the last screen matters for what must be hidden
+			screens := strings.Split(out.String(), ansiClear)
+			last := screens[len(screens)-2]
+			for _, unexpected := range tc.notContains {
+				if strings.Contains(last, unexpected) {
+					t.Errorf("Expected output not to contain %q, got:\n%s", unexpected, last)
+				}
+			}
+		})
+	}
+}
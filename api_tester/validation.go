@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ValidationConfig holds the configurable product rules, loaded from the suite's "validation" section
+type ValidationConfig struct {
+	// Prices enables the currency-aware price checks; they are skipped when nil
+	Prices *PriceRules `json:"prices,omitempty"`
+	// Text enables the title and description quality checks; they are skipped when nil
+	Text *TextRules `json:"text,omitempty"`
+}
+
+// PriceRules configures currency-aware price checks
+type PriceRules struct {
+	// DefaultCurrency applies to products without a currency field
+	DefaultCurrency string `json:"default_currency,omitempty"`
+	// Precision overrides the number of minor-unit digits allowed per currency
+	Precision map[string]int `json:"precision,omitempty"`
+	// MaxPrice is the highest plausible price per currency; "*" applies to all others
+	MaxPrice map[string]float64 `json:"max_price,omitempty"`
+	// FloatArtifacts reports prices like 19.989999 that are a rounding error away from a valid
+	// price; enabled unless set to false
+	FloatArtifacts *bool `json:"float_artifacts,omitempty"`
+}
+
+// TextRules configures the quality checks on titles and descriptions. Once text checks are
+// enabled, every check runs unless its flag is set to false; length bounds only apply when
+// configured
+type TextRules struct {
+	// MinLength and MaxLength bound the length in characters, keyed by field (title, description)
+	MinLength map[string]int `json:"min_length,omitempty"`
+	MaxLength map[string]int `json:"max_length,omitempty"`
+	// ControlChars reports control characters (tabs and line breaks are allowed in descriptions)
+	ControlChars *bool `json:"control_chars,omitempty"`
+	// InvalidUTF8 reports U+FFFD, which the JSON decoder substitutes for invalid UTF-8
+	InvalidUTF8 *bool `json:"invalid_utf8,omitempty"`
+	// HTML reports markup such as <b> or <script>
+	HTML       *bool `json:"html,omitempty"`
+	Whitespace *bool `json:"whitespace,omitempty"`
+	// DuplicateWords reports a word repeated back to back, as in "the the"
+	DuplicateWords *bool `json:"duplicate_words,omitempty"`
+	Placeholders   *bool `json:"placeholders,omitempty"`
+	// PlaceholderPatterns replaces the default placeholder phrases
+	PlaceholderPatterns []string `json:"placeholder_patterns,omitempty"`
+}
+
+// defaultPlaceholders are phrases that indicate unfinished content
+var defaultPlaceholders = []string{"lorem ipsum", "TODO", "TBD", "FIXME", "placeholder"}
+
+// htmlTagPattern matches opening and closing HTML tags
+var htmlTagPattern = regexp.MustCompile(`(?i)<\s*/?\s*([a-z][a-z0-9]*)\b[^>]*>`)
+
+// duplicateWordPattern splits text into words for the duplicate check
+var duplicateWordPattern = regexp.MustCompile(`[\p{L}\p{N}']+`)
+
+// defaultCurrency is assumed when neither the product nor the rules name a currency
+const defaultCurrency = "USD"
+
+// currencyPrecision lists ISO 4217 currencies whose minor unit isn't two digits
+var currencyPrecision = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// currency returns the product's currency, falling back to the configured default
+func (r PriceRules) currency(product Product) string {
+	switch {
+	case product.Currency != "":
+		return strings.ToUpper(product.Currency)
+	case r.DefaultCurrency != "":
+		return strings.ToUpper(r.DefaultCurrency)
+	}
+	return defaultCurrency
+}
+
+// precision returns the number of decimal places allowed for a currency
+func (r PriceRules) precision(currency string) int {
+	if digits, ok := r.Precision[currency]; ok {
+		return digits
+	}
+	if digits, ok := currencyPrecision[currency]; ok {
+		return digits
+	}
+	return 2
+}
+
+// maxPrice returns the price threshold for a currency, if any
+func (r PriceRules) maxPrice(currency string) (float64, bool) {
+	if limit, ok := r.MaxPrice[currency]; ok {
+		return limit, true
+	}
+	limit, ok := r.MaxPrice["*"]
+	return limit, ok
+}
+
+// isCurrencyCode reports whether code looks like an ISO 4217 code
+func isCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// decimalPlaces counts the digits after the decimal point in the shortest representation of value
+func decimalPlaces(value float64) int {
+	formatted := strconv.FormatFloat(value, 'f', -1, 64)
+	if i := strings.IndexByte(formatted, '.'); i >= 0 {
+		return len(formatted) - i - 1
+	}
+	return 0
+}
+
+// validatePrice applies the currency-aware price rules to a product
+func validatePrice(product Product, rules PriceRules) []ValidationError {
+	var errors []ValidationError
+	newError := func(field, message, severity string, value interface{}) {
+		errors = append(errors, ValidationError{
+			ProductID:   product.ID,
+			Title:       product.Title,
+			Field:       field,
+			Message:     message,
+			Severity:    severity,
+			ActualValue: value,
+		})
+	}
+
+	currency := rules.currency(product)
+	if !isCurrencyCode(currency) {
+		newError("currency", "Currency is not a three-letter ISO 4217 code", SeverityMajor, currency)
+		return errors
+	}
+
+	// Compare the price with its value rounded to the currency's minor unit
+	digits := rules.precision(currency)
+	if places := decimalPlaces(product.Price); places > digits {
+		scale := math.Pow(10, float64(digits))
+		rounded := math.Round(product.Price*scale) / scale
+		// A long tail that is tiny compared to the minor unit is a float artifact, not a bad price
+		artifact := places >= digits+4 && math.Abs(product.Price-rounded) < 0.5*math.Pow(10, -float64(digits+3))
+		switch {
+		case !artifact:
+			newError("price", fmt.Sprintf("Price is more precise than %s allows (%d decimal places)", currency, digits), SeverityMajor, product.Price)
+		case rules.FloatArtifacts == nil || *rules.FloatArtifacts:
+			newError("price", fmt.Sprintf("Price looks like a float artifact of %s %s", strconv.FormatFloat(rounded, 'f', digits, 64), currency), SeverityMinor, product.Price)
+		}
+	}
+
+	if limit, ok := rules.maxPrice(currency); ok && product.Price > limit {
+		newError("price", fmt.Sprintf("Price exceeds the %s maximum of %s", currency, strconv.FormatFloat(limit, 'f', -1, 64)), SeverityMajor, product.Price)
+	}
+
+	return errors
+}
+
+// enabled reports whether an optional check flag is on; unset flags default to on
+func enabled(flag *bool) bool {
+	return flag == nil || *flag
+}
+
+// validateText applies the text quality rules to a product's title and description
+func validateText(product Product, rules TextRules) []ValidationError {
+	var errors []ValidationError
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"title", product.Title},
+		{"description", product.Description},
+	}
+
+	for _, field := range fields {
+		// Empty and whitespace-only values are reported by the basic checks
+		if strings.TrimSpace(field.value) == "" {
+			continue
+		}
+		newError := func(message, severity string) {
+			errors = append(errors, ValidationError{
+				ProductID:   product.ID,
+				Title:       product.Title,
+				Field:       field.name,
+				Message:     message,
+				Severity:    severity,
+				ActualValue: field.value,
+			})
+		}
+
+		length := utf8.RuneCountInString(field.value)
+		if min, ok := rules.MinLength[field.name]; ok && length < min {
+			newError(fmt.Sprintf("Length %d is below the minimum of %d", length, min), SeverityMajor)
+		}
+		if max, ok := rules.MaxLength[field.name]; ok && length > max {
+			newError(fmt.Sprintf("Length %d exceeds the maximum of %d", length, max), SeverityMajor)
+		}
+
+		if enabled(rules.ControlChars) {
+			for _, r := range field.value {
+				if r == utf8.RuneError || !unicode.IsControl(r) {
+					continue
+				}
+				if field.name == "description" && (r == '\n' || r == '\r' || r == '\t') {
+					continue
+				}
+				newError(fmt.Sprintf("Contains control character %U", r), SeverityMajor)
+				break
+			}
+		}
+
+		if enabled(rules.InvalidUTF8) && strings.ContainsRune(field.value, utf8.RuneError) {
+			newError("Contains invalid UTF-8 (replacement character U+FFFD)", SeverityMajor)
+		}
+
+		if enabled(rules.HTML) {
+			if match := htmlTagPattern.FindStringSubmatch(field.value); match != nil {
+				if strings.EqualFold(match[1], "script") || strings.Contains(strings.ToLower(field.value), "<script") {
+					newError("Contains a script tag", SeverityCritical)
+				} else {
+					newError(fmt.Sprintf("Contains HTML tag %s", match[0]), SeverityMajor)
+				}
+			}
+		}
+
+		if enabled(rules.Whitespace) && strings.TrimSpace(field.value) != field.value {
+			newError("Has leading or trailing whitespace", SeverityMinor)
+		}
+
+		if enabled(rules.DuplicateWords) {
+			words := duplicateWordPattern.FindAllString(field.value, -1)
+			for i := 1; i < len(words); i++ {
+				if strings.EqualFold(words[i], words[i-1]) {
+					newError(fmt.Sprintf("Repeats the word %q", words[i]), SeverityMinor)
+					break
+				}
+			}
+		}
+
+		if enabled(rules.Placeholders) {
+			patterns := rules.PlaceholderPatterns
+			if len(patterns) == 0 {
+				patterns = defaultPlaceholders
+			}
+			for _, pattern := range patterns {
+				if containsPhrase(field.value, pattern) {
+					newError(fmt.Sprintf("Contains placeholder text %q", pattern), SeverityMajor)
+					break
+				}
+			}
+		}
+	}
+
+	return errors
+}
+
+// containsPhrase reports whether phrase occurs in text as whole words, ignoring case
+func containsPhrase(text, phrase string) bool {
+	pattern, err := regexp.Compile(`(?i)(^|[^\p{L}\p{N}])` + regexp.QuoteMeta(phrase) + `($|[^\p{L}\p{N}])`)
+	if err != nil {
+		return false
+	}
+	return pattern.MatchString(text)
+}
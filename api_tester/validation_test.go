@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestValidatePrice(t *testing.T) {
+	disabled := false
+
+	testCases := []struct {
+		name            string
+		product         Product
+		rules           PriceRules
+		expectedMessage []string
+	}{
+		{"Valid USD price", Product{Price: 19.99}, PriceRules{}, nil},
+		{"Too many decimals", Product{Price: 19.995}, PriceRules{}, []string{"Price is more precise than USD allows (2 decimal places)"}},
+		{"Float artifact", Product{Price: 19.989999}, PriceRules{}, []string{"Price looks like a float artifact of 19.99 USD"}},
+		{"Float artifact check disabled", Product{Price: 19.989999}, PriceRules{FloatArtifacts: &disabled}, nil},
+		{"JPY has no minor units", Product{Price: 1500.5, Currency: "JPY"}, PriceRules{}, []string{"Price is more precise than JPY allows (0 decimal places)"}},
+		{"Whole JPY price", Product{Price: 1500, Currency: "JPY"}, PriceRules{}, nil},
+		{"Three-digit currency", Product{Price: 1.125, Currency: "KWD"}, PriceRules{}, nil},
+		{"Configured precision", Product{Price: 1.5, Currency: "EUR"}, PriceRules{Precision: map[string]int{"EUR": 0}}, []string{"Price is more precise than EUR allows (0 decimal places)"}},
+		{"Default currency", Product{Price: 10.5}, PriceRules{DefaultCurrency: "jpy"}, []string{"Price is more precise than JPY allows (0 decimal places)"}},
+		{"Invalid currency", Product{Price: 10, Currency: "dollars"}, PriceRules{}, []string{"Currency is not a three-letter ISO 4217 code"}},
+		{"Above maximum", Product{Price: 20000, Currency: "USD"}, PriceRules{MaxPrice: map[string]float64{"USD": 10000}}, []string{"Price exceeds the USD maximum of 10000"}},
+		{"Lowercase currency", Product{Price: 1500, Currency: "jpy"}, PriceRules{DefaultCurrency: "usd"}, nil},
+		{"Lowercase currency precision", Product{Price: 19.995, Currency: "usd"}, PriceRules{}, []string{"Price is more precise than USD allows (2 decimal places)"}},
+		{"Wildcard maximum", Product{Price: 600, Currency: "GBP"}, PriceRules{MaxPrice: map[string]float64{"USD": 1000, "*": 500}}, []string{"Price exceeds the GBP maximum of 500"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			errors := validatePrice(tc.product, tc.rules)
+			if len(errors) != len(tc.expectedMessage) {
+				t.Fatalf("Expected %d errors, got %d: %+v", len(tc.expectedMessage), len(errors), errors)
+			}
+			for i, err := range errors {
+				if err.Message != tc.expectedMessage[i] {
+					t.Errorf("Expected message %q, got %q", tc.expectedMessage[i], err.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateText(t *testing.T) {
+	disabled := false
+
+	testCases := []struct {
+		name            string
+		product         Product
+		rules           TextRules
+		expectedMessage []string
+	}{
+		{"Clean text", Product{Title: "Cotton Shirt", Description: "A soft shirt.\nMachine washable."}, TextRules{}, nil},
+		{"Too short", Product{Title: "Hat"}, TextRules{MinLength: map[string]int{"title": 5}}, []string{"Length 3 is below the minimum of 5"}},
+		{"Too long counts characters", Product{Title: "Thé vert"}, TextRules{MaxLength: map[string]int{"title": 7}}, []string{"Length 8 exceeds the maximum of 7"}},
+		{"Control character in title", Product{Title: "Cotton\tShirt"}, TextRules{}, []string{"Contains control character U+0009"}},
+		{"Control character in description", Product{Description: "Soft\x07shirt"}, TextRules{}, []string{"Contains control character U+0007"}},
+		{"Invalid UTF-8", Product{Title: "Caf�"}, TextRules{}, []string{"Contains invalid UTF-8 (replacement character U+FFFD)"}},
+		{"HTML tag", Product{Description: "A <b>bold</b> claim"}, TextRules{}, []string{"Contains HTML tag <b>"}},
+		{"Script tag", Product{Description: "Nice<script>alert(1)</script>"}, TextRules{}, []string{"Contains a script tag"}},
+		{"Leading and trailing whitespace", Product{Title: " Shirt "}, TextRules{}, []string{"Has leading or trailing whitespace"}},
+		{"Duplicated word", Product{Description: "Made of of cotton"}, TextRules{}, []string{`Repeats the word "of"`}},
+		{"Lorem ipsum", Product{Description: "Lorem ipsum dolor sit amet"}, TextRules{}, []string{`Contains placeholder text "lorem ipsum"`}},
+		{"TODO placeholder", Product{Title: "TODO: name"}, TextRules{}, []string{`Contains placeholder text "TODO"`}},
+		{"Placeholder inside a word", Product{Title: "Mastodon Mug"}, TextRules{}, nil},
+		{"Custom placeholders", Product{Title: "Sample item"}, TextRules{PlaceholderPatterns: []string{"sample"}}, []string{`Contains placeholder text "sample"`}},
+		{"Disabled checks", Product{Title: " <i>TODO</i> the the "}, TextRules{HTML: &disabled, Whitespace: &disabled, DuplicateWords: &disabled, Placeholders: &disabled}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			errors := validateText(tc.product, tc.rules)
+			if len(errors) != len(tc.expectedMessage) {
+				t.Fatalf("Expected %d errors, got %d: %+v", len(tc.expectedMessage), len(errors), errors)
+			}
+			for i, err := range errors {
+				if err.Message != tc.expectedMessage[i] {
+					t.Errorf("Expected message %q, got %q", tc.expectedMessage[i], err.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateProductsOptionalChecks(t *testing.T) {
+	products := []Product{{ID: 1, Title: "Shirt", Price: 19.995, Description: "TODO", Rating: Rating{Rate: 4, Count: 1}}}
+
+	if errors := validateProducts(products, ValidationConfig{}); len(errors) != 0 {
+		t.Errorf("Expected no defects without price and text rules, got %+v", errors)
+	}
+
+	if errors := validateProducts(products, ValidationConfig{Prices: &PriceRules{}}); len(errors) != 1 || errors[0].Field != "price" {
+		t.Errorf("Expected a price defect with price rules, got %+v", errors)
+	}
+
+	if errors := validateProducts(products, ValidationConfig{Text: &TextRules{}}); len(errors) != 1 || errors[0].Field != "description" {
+		t.Errorf("Expected a description defect with text rules, got %+v", errors)
+	}
+}
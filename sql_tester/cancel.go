@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// runningQueries tracks the custom queries in progress so that they can be cancelled by ID
+type runningQueries struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+// newRunningQueries creates an empty registry
+func newRunningQueries() *runningQueries {
+	return &runningQueries{cancels: make(map[string]context.CancelFunc)}
+}
+
+// start registers a query under id and returns the context to run it with. An empty id
+// gets a random one. done must be called when the query finishes
+func (q *runningQueries) start(ctx context.Context, id string) (string, context.Context, func(), error) {
+	if id == "" {
+		id = newQueryID()
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.cancels[id]; ok {
+		return "", nil, nil, fmt.Errorf("a query with id %q is already running", id)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	q.cancels[id] = cancel
+	done := func() {
+		q.mu.Lock()
+		delete(q.cancels, id)
+		q.mu.Unlock()
+		cancel()
+	}
+	return id, ctx, done, nil
+}
+
+// cancel aborts the query running under id and reports whether there was one
+func (q *runningQueries) cancel(id string) bool {
+	q.mu.Lock()
+	cancel, ok := q.cancels[id]
+	q.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// newQueryID returns a random identifier for a query started without one
+func newQueryID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// cancelQuery aborts a running custom query given the id it was started with
+func cancelQuery(w http.ResponseWriter, r *http.Request, running *runningQueries) {
+	// Only accept POST requests
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse the request body
+	var request struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("Error parsing request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !running.cancel(request.ID) {
+		// The query may simply have finished already
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": fmt.Sprintf("No running query with id %q", request.ID),
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":        request.ID,
+		"cancelled": true,
+	})
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCancelQuery(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	running := newRunningQueries()
+	limits := QueryLimits{ReadOnly: true}
+
+	post := func(handler func(w http.ResponseWriter, r *http.Request), body string) (int, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		var decoded map[string]interface{}
+		json.NewDecoder(rec.Body).Decode(&decoded)
+		return rec.Code, decoded
+	}
+	query := func(w http.ResponseWriter, r *http.Request) { executeQuery(w, r, db, "sqlite", limits, running) }
+	cancel := func(w http.ResponseWriter, r *http.Request) { cancelQuery(w, r, running) }
+
+	// Start a query that never ends on its own
+	finished := make(chan map[string]interface{})
+	go func() {
+		_, body := post(query, `{"id": "endless", "query": "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT COUNT(*) FROM n"}`)
+		finished <- body
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		running.mu.Lock()
+		_, started := running.cancels["endless"]
+		running.mu.Unlock()
+		if started {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Query was never registered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// The id is taken while the query runs
+	if code, body := post(query, `{"id": "endless", "query": "SELECT 1"}`); code != http.StatusConflict {
+		t.Errorf("Expected status %d for a duplicate id, got %d (%v)", http.StatusConflict, code, body)
+	}
+
+	if code, body := post(cancel, `{"id": "endless"}`); code != http.StatusOK || body["cancelled"] != true {
+		t.Errorf("Expected the query to be cancelled, got %d (%v)", code, body)
+	}
+	select {
+	case body := <-finished:
+		if body["error"] != "query was cancelled" || body["id"] != "endless" {
+			t.Errorf("Expected a cancelled error for endless, got %v", body)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Query did not stop after being cancelled")
+	}
+
+	// Finished queries are unregistered
+	if code, _ := post(cancel, `{"id": "endless"}`); code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a finished query, got %d", http.StatusNotFound, code)
+	}
+	if code, body := post(query, `{"query": "SELECT 1"}`); code != http.StatusOK || body["id"] == "" {
+		t.Errorf("Expected a generated id, got %d (%v)", code, body)
+	}
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql" // MySQL driver
+	_ "github.com/lib/pq"              // PostgreSQL driver
+)
+
+// dialects maps the supported database/sql drivers to the dialect of their fixtures
+var dialects = map[string]string{
+	"sqlite3":  "sqlite",
+	"postgres": "postgres",
+	"mysql":    "mysql",
+}
+
+// Engine is a database the suite runs against
+type Engine struct {
+	// Name labels the engine in reports
+	Name string
+	// Driver is the database/sql driver: sqlite3, postgres or mysql
+	Driver string
+	// DSN is the connection string; for sqlite3 it is the database file, and empty
+	// means a temporary database
+	DSN string
+}
+
+// Dialect returns the SQL dialect spoken by the engine's driver
+func (e Engine) Dialect() string {
+	return dialects[e.Driver]
+}
+
+// parseEngine reads an -engine flag of the form driver=dsn
+func parseEngine(spec string) (Engine, error) {
+	driver, dsn := spec, ""
+	if i := strings.IndexByte(spec, '='); i >= 0 {
+		driver, dsn = spec[:i], spec[i+1:]
+	}
+	engine := Engine{Driver: strings.TrimSpace(driver), DSN: dsn}
+	if engine.Dialect() == "" {
+		return Engine{}, fmt.Errorf("unknown driver %q (supported: sqlite3, postgres, mysql)", engine.Driver)
+	}
+	if engine.DSN == "" && engine.Dialect() != "sqlite" {
+		return Engine{}, fmt.Errorf("driver %s needs a connection string, as in %s=<dsn>", engine.Driver, engine.Driver)
+	}
+	engine.Name = engine.Dialect()
+	return engine, nil
+}
+
+// engineList collects repeated -engine flags
+type engineList []Engine
+
+func (l *engineList) String() string {
+	names := make([]string, len(*l))
+	for i, engine := range *l {
+		names[i] = engine.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func (l *engineList) Set(spec string) error {
+	engine, err := parseEngine(spec)
+	if err != nil {
+		return err
+	}
+	*l = append(*l, engine)
+	return nil
+}
+
+// uniqueNames numbers engines sharing a dialect, as in "sqlite" and "sqlite-2"
+func uniqueNames(engines []Engine) []Engine {
+	seen := map[string]int{}
+	for i := range engines {
+		seen[engines[i].Name]++
+		if n := seen[engines[i].Name]; n > 1 {
+			engines[i].Name = fmt.Sprintf("%s-%d", engines[i].Name, n)
+		}
+	}
+	return engines
+}
+
+// setupOptions control how openEngine prepares a database
+type setupOptions struct {
+	SchemaPath string
+	SeedPath   string
+	Overwrite  bool
+	NoFixtures bool
+}
+
+// openEngine connects to an engine and applies the fixtures for its dialect. SQLite
+// without a DSN gets a temporary database, whose path is stored in engine.DSN. Existing
+// data is never modified unless options.Overwrite is set. The returned cleanup function
+// closes the connection and removes a temporary database
+func openEngine(engine *Engine, options setupOptions) (*sql.DB, Fixtures, func(), error) {
+	fixtures, err := loadFixtures(options.SchemaPath, options.SeedPath, engine.Dialect())
+	if err != nil {
+		return nil, Fixtures{}, nil, fmt.Errorf("failed to load fixtures: %w", err)
+	}
+
+	cleanup := func() {}
+	if engine.Dialect() == "sqlite" {
+		if engine.DSN == "" {
+			if options.NoFixtures {
+				return nil, Fixtures{}, nil, fmt.Errorf("-no-fixtures needs an existing database given with -db")
+			}
+			tempFile, err := os.CreateTemp("", "sql_tester-*.db")
+			if err != nil {
+				return nil, Fixtures{}, nil, fmt.Errorf("failed to create temporary database: %w", err)
+			}
+			tempFile.Close()
+			engine.DSN = tempFile.Name()
+			path := engine.DSN
+			cleanup = func() { os.Remove(path) }
+		} else if info, err := os.Stat(engine.DSN); err == nil && info.Size() > 0 && !options.Overwrite && !options.NoFixtures {
+			return nil, Fixtures{}, nil, fmt.Errorf("refusing to modify existing database %s: pass -overwrite to recreate the tables %s, or -no-fixtures to use it as is",
+				engine.DSN, strings.Join(fixtures.Tables(), ", "))
+		}
+	}
+
+	db, err := sql.Open(engine.Driver, engine.DSN)
+	if err != nil {
+		cleanup()
+		return nil, Fixtures{}, nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	closeAll := func() {
+		db.Close()
+		cleanup()
+	}
+	if err := db.Ping(); err != nil {
+		closeAll()
+		return nil, Fixtures{}, nil, fmt.Errorf("failed to connect to %s: %w", engine.Name, err)
+	}
+
+	if options.NoFixtures {
+		return db, Fixtures{}, closeAll, nil
+	}
+
+	// Server databases have no file to check, so look for the fixture tables instead
+	if engine.Dialect() != "sqlite" && !options.Overwrite {
+		existing, err := listTables(db, engine.Dialect())
+		if err != nil {
+			closeAll()
+			return nil, Fixtures{}, nil, err
+		}
+		if clash := commonTables(existing, fixtures.Tables()); len(clash) > 0 {
+			closeAll()
+			return nil, Fixtures{}, nil, fmt.Errorf("refusing to modify existing tables %s in %s: pass -overwrite to recreate them, or -no-fixtures to use them as is",
+				strings.Join(clash, ", "), engine.Name)
+		}
+	}
+
+	if err := applyFixtures(db, fixtures, options.Overwrite); err != nil {
+		closeAll()
+		return nil, Fixtures{}, nil, err
+	}
+	return db, fixtures, closeAll, nil
+}
+
+// commonTables returns the names found in both lists, ignoring case
+func commonTables(existing, wanted []string) []string {
+	present := map[string]bool{}
+	for _, table := range existing {
+		present[strings.ToLower(table)] = true
+	}
+	var common []string
+	for _, table := range wanted {
+		if present[strings.ToLower(table)] {
+			common = append(common, table)
+		}
+	}
+	return common
+}
+
+// listTables returns the user tables of the database, sorted by name
+func listTables(db queryer, dialect string) ([]string, error) {
+	var query string
+	switch dialect {
+	case "sqlite":
+		query = "SELECT name FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%' ORDER BY name"
+	case "postgres":
+		query = "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() AND table_type = 'BASE TABLE' ORDER BY table_name"
+	default:
+		query = "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE' ORDER BY table_name"
+	}
+
+	rows, err := db.QueryContext(context.Background(), query)
+	if err != nil {
+		return nil, fmt.Errorf("error getting tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("error scanning table name: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// describeColumns returns the name, type, nullability, primary key flag and default of
+// every column of a table
+func describeColumns(db *sql.DB, dialect, table string) ([]map[string]interface{}, error) {
+	var columns []map[string]interface{}
+
+	if dialect == "sqlite" {
+		rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var cid int
+			var name, typeName string
+			var notNull, pk int
+			var dfltValue interface{}
+			if err := rows.Scan(&cid, &name, &typeName, &notNull, &dfltValue, &pk); err != nil {
+				continue
+			}
+			columns = append(columns, map[string]interface{}{
+				"name":     name,
+				"type":     typeName,
+				"not_null": notNull == 1,
+				"pk":       pk == 1,
+				"default":  normalizeValue(dfltValue),
+			})
+		}
+		return columns, rows.Err()
+	}
+
+	schema, placeholder := "DATABASE()", "?"
+	if dialect == "postgres" {
+		schema, placeholder = "current_schema()", "$1"
+	}
+	query := `SELECT c.column_name, c.data_type, c.is_nullable, c.column_default,
+	EXISTS (SELECT 1 FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage k
+			ON k.constraint_name = tc.constraint_name AND k.table_schema = tc.table_schema AND k.table_name = tc.table_name
+		WHERE tc.constraint_type = 'PRIMARY KEY' AND k.table_schema = c.table_schema
+			AND k.table_name = c.table_name AND k.column_name = c.column_name)
+FROM information_schema.columns c
+WHERE c.table_schema = ` + schema + ` AND c.table_name = ` + placeholder + `
+ORDER BY c.ordinal_position`
+
+	rows, err := db.Query(query, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, typeName, nullable string
+		var dfltValue interface{}
+		var pk bool
+		if err := rows.Scan(&name, &typeName, &nullable, &dfltValue, &pk); err != nil {
+			continue
+		}
+		columns = append(columns, map[string]interface{}{
+			"name":     name,
+			"type":     typeName,
+			"not_null": nullable == "NO",
+			"pk":       pk,
+			"default":  normalizeValue(dfltValue),
+		})
+	}
+	return columns, rows.Err()
+}
+
+// Divergence is a test whose outcome on an engine differs from the first engine's
+type Divergence struct {
+	Test   string `json:"test"`
+	Base   string `json:"base"`
+	Engine string `json:"engine"`
+	// Error is set when the test fails to run on only one of the two engines
+	Error string    `json:"error,omitempty"`
+	Diff  []RowDiff `json:"diff,omitempty"`
+}
+
+// compareEngines checks every engine's results against the first engine's. results[i][j]
+// is the outcome of queries[j] on engines[i]. Result sets are compared without regard to
+// row order unless the test is ordered, and column names only when the test names them,
+// since engines label unaliased expressions differently
+func compareEngines(queries []Query, engines []Engine, results [][]TestResult) []Divergence {
+	var divergences []Divergence
+	for j, q := range queries {
+		for i := 1; i < len(engines); i++ {
+			base, other := results[0][j], results[i][j]
+			divergence := Divergence{Test: q.Name, Base: engines[0].Name, Engine: engines[i].Name}
+
+			switch {
+			case base.Error != "" && other.Error != "":
+				// Failing everywhere is consistent, even though the messages differ
+				continue
+			case base.Error != "":
+				divergence.Error = fmt.Sprintf("fails on %s: %s", engines[0].Name, base.Error)
+			case other.Error != "":
+				divergence.Error = fmt.Sprintf("fails on %s: %s", engines[i].Name, other.Error)
+			default:
+				expected := Expectation{Rows: base.Actual.Rows}
+				if q.Expected != nil {
+					expected.Ordered = q.Expected.Ordered
+					expected.Tolerance = q.Expected.Tolerance
+					if len(q.Expected.Columns) > 0 {
+						expected.Columns = base.Actual.Columns
+					}
+				}
+				if base.Affected != other.Affected {
+					divergence.Diff = append(divergence.Diff, RowDiff{Kind: "affected", Expected: []interface{}{base.Affected}, Actual: []interface{}{other.Affected}})
+				}
+				divergence.Diff = append(divergence.Diff, compareResults(expected, other.Actual)...)
+				if len(divergence.Diff) == 0 {
+					continue
+				}
+			}
+			divergences = append(divergences, divergence)
+		}
+	}
+
+	return divergences
+}
+
+// printDivergences displays the cross-engine comparison
+func printDivergences(queries []Query, engines []Engine, divergences []Divergence) {
+	names := make([]string, len(engines))
+	for i, engine := range engines {
+		names[i] = engine.Name
+	}
+	fmt.Printf("Cross-engine comparison (%s)\n", strings.Join(names, ", "))
+	fmt.Println("================================")
+
+	for _, q := range queries {
+		diverged := false
+		for _, d := range divergences {
+			if d.Test != q.Name {
+				continue
+			}
+			diverged = true
+			if d.Error != "" {
+				fmt.Printf("❌ %s: %s\n", q.Name, d.Error)
+				continue
+			}
+			fmt.Printf("❌ %s: %s differs from %s (%d difference(s)):\n", q.Name, d.Engine, d.Base, len(d.Diff))
+			printDiff(d.Diff)
+		}
+		if !diverged {
+			fmt.Printf("✅ %s: same result on every engine\n", q.Name)
+		}
+	}
+	fmt.Println()
+}
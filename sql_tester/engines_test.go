@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEngine(t *testing.T) {
+	testCases := []struct {
+		name        string
+		spec        string
+		expected    Engine
+		expectError bool
+	}{
+		{"PostgreSQL URL", "postgres=postgres://user:pw@localhost/sales?sslmode=disable", Engine{Name: "postgres", Driver: "postgres", DSN: "postgres://user:pw@localhost/sales?sslmode=disable"}, false},
+		{"MySQL DSN", "mysql=user:pw@tcp(localhost:3306)/sales", Engine{Name: "mysql", Driver: "mysql", DSN: "user:pw@tcp(localhost:3306)/sales"}, false},
+		{"Temporary SQLite", "sqlite3", Engine{Name: "sqlite", Driver: "sqlite3"}, false},
+		{"Unknown driver", "oracle=scott/tiger", Engine{}, true},
+		{"Server without DSN", "postgres=", Engine{}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			engine, err := parseEngine(tc.spec)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected an error, got %+v", engine)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if engine != tc.expected {
+				t.Errorf("Expected %+v, got %+v", tc.expected, engine)
+			}
+		})
+	}
+
+	engines := uniqueNames([]Engine{{Name: "sqlite"}, {Name: "postgres"}, {Name: "sqlite"}})
+	if engines[0].Name != "sqlite" || engines[2].Name != "sqlite-2" {
+		t.Errorf("Expected sqlite and sqlite-2, got %s and %s", engines[0].Name, engines[2].Name)
+	}
+}
+
+func TestLoadFixturesDialect(t *testing.T) {
+	// Built-in fixtures have PostgreSQL and MySQL schemas, and share the seed data
+	for dialect, keyword := range map[string]string{"sqlite": "INTEGER PRIMARY KEY", "postgres": "SERIAL", "mysql": "AUTO_INCREMENT"} {
+		fixtures, err := loadFixtures("", "", dialect)
+		if err != nil {
+			t.Fatalf("Failed to load %s fixtures: %v", dialect, err)
+		}
+		if !strings.Contains(fixtures.Schema, keyword) {
+			t.Errorf("Expected the %s schema to contain %s, got:\n%s", dialect, keyword, fixtures.Schema)
+		}
+		if fixtures.Seed != defaultSeed {
+			t.Errorf("Expected the %s fixtures to use the default seed", dialect)
+		}
+	}
+
+	// A custom file's dialect variant wins over the file itself
+	tempDir, err := os.MkdirTemp("", "fixtures-dialect-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	schemaPath := filepath.Join(tempDir, "schema.sql")
+	os.WriteFile(schemaPath, []byte("CREATE TABLE generic (id INTEGER)"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "schema.postgres.sql"), []byte("CREATE TABLE pg (id SERIAL)"), 0644)
+
+	fixtures, _ := loadFixtures(schemaPath, "", "postgres")
+	if !reflect.DeepEqual(fixtures.Tables(), []string{"pg"}) {
+		t.Errorf("Expected the postgres variant, got tables %v", fixtures.Tables())
+	}
+	fixtures, _ = loadFixtures(schemaPath, "", "mysql")
+	if !reflect.DeepEqual(fixtures.Tables(), []string{"generic"}) {
+		t.Errorf("Expected the generic schema for mysql, got tables %v", fixtures.Tables())
+	}
+}
+
+func TestOpenEngine(t *testing.T) {
+	// Without a DSN, SQLite gets a temporary database that cleanup removes
+	engine := Engine{Name: "sqlite", Driver: "sqlite3"}
+	db, fixtures, cleanup, err := openEngine(&engine, setupOptions{})
+	if err != nil {
+		t.Fatalf("Failed to open engine: %v", err)
+	}
+	if engine.DSN == "" || len(fixtures.Tables()) != 1 {
+		t.Errorf("Expected a temporary database with the orders fixture, got %q and %v", engine.DSN, fixtures.Tables())
+	}
+	tables, err := listTables(db, engine.Dialect())
+	if err != nil || !reflect.DeepEqual(tables, []string{"orders"}) {
+		t.Errorf("Expected tables [orders], got %v (%v)", tables, err)
+	}
+	columns, err := describeColumns(db, engine.Dialect(), "orders")
+	if err != nil || len(columns) != 4 || columns[0]["pk"] != true {
+		t.Errorf("Expected 4 columns with id as primary key, got %v (%v)", columns, err)
+	}
+
+	// The populated file is now refused without -overwrite
+	existing := Engine{Name: "sqlite", Driver: "sqlite3", DSN: engine.DSN}
+	if _, _, _, err := openEngine(&existing, setupOptions{}); err == nil {
+		t.Errorf("Expected an existing database to be refused")
+	}
+
+	cleanup()
+	if _, err := os.Stat(engine.DSN); !os.IsNotExist(err) {
+		t.Errorf("Expected the temporary database to be removed, got %v", err)
+	}
+}
+
+func TestCompareEngines(t *testing.T) {
+	// A second SQLite database with an extra order stands in for another engine
+	base := openTestDB(t)
+	defer base.Close()
+	other := openTestDB(t)
+	defer other.Close()
+	if _, err := other.Exec("INSERT INTO orders (customer, amount, order_date) VALUES ('Dana', 1000, '2024-03-10')"); err != nil {
+		t.Fatalf("Failed to add order: %v", err)
+	}
+
+	queries := []Query{
+		{Name: "Customers", SQL: "SELECT DISTINCT customer FROM orders"},
+		{Name: "Bob's orders", SQL: "SELECT amount FROM orders WHERE customer = 'Bob'"},
+		{Name: "Missing table", SQL: "SELECT * FROM missing"},
+		{Name: "Dialect only", SQL: "SELECT strftime('%Y', order_date) FROM orders LIMIT 1"},
+	}
+	engines := []Engine{{Name: "sqlite"}, {Name: "stand-in"}}
+	results := make([][]TestResult, 2)
+	for i, db := range []*sql.DB{base, other} {
+		for _, q := range queries {
+			results[i] = append(results[i], runTest(context.Background(), db, q))
+		}
+	}
+	// Simulate an engine lacking a function the first one has
+	results[1][3] = TestResult{Name: "Dialect only", Error: "no such function: strftime"}
+
+	divergences := compareEngines(queries, engines, results)
+	if len(divergences) != 2 {
+		t.Fatalf("Expected 2 divergences, got %+v", divergences)
+	}
+	if d := divergences[0]; d.Test != "Customers" || len(d.Diff) != 1 || d.Diff[0].Kind != "unexpected" || d.Diff[0].Actual[0] != "Dana" {
+		t.Errorf("Expected the stand-in's extra customer Dana, got %+v", d)
+	}
+	if d := divergences[1]; d.Test != "Dialect only" || !strings.Contains(d.Error, "fails on stand-in") {
+		t.Errorf("Expected the stand-in to fail the dialect test, got %+v", d)
+	}
+}
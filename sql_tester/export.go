@@ -0,0 +1,446 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportFormat describes one of the formats results can be exported to
+type exportFormat struct {
+	ContentType string
+	Extension   string
+}
+
+// exportFormats lists the supported export formats by name
+var exportFormats = map[string]exportFormat{
+	"csv":   {"text/csv; charset=utf-8", "csv"},
+	"tsv":   {"text/tab-separated-values; charset=utf-8", "tsv"},
+	"jsonl": {"application/x-ndjson", "jsonl"},
+	"xlsx":  {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xlsx"},
+}
+
+// exportFormatNames returns the supported format names, sorted
+func exportFormatNames() []string {
+	names := make([]string, 0, len(exportFormats))
+	for name := range exportFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// exportWriter writes a result in an export format as its rows arrive, so that nothing
+// but the current row is held in memory
+type exportWriter interface {
+	Begin(columns []string) error
+	Row(values []interface{}) error
+	// Close completes the output; it doesn't close the underlying writer
+	Close() error
+}
+
+// newExportWriter creates the writer for a format
+func newExportWriter(format string, w io.Writer) (exportWriter, error) {
+	switch format {
+	case "csv", "tsv":
+		writer := csv.NewWriter(w)
+		if format == "tsv" {
+			writer.Comma = '\t'
+		}
+		return &csvExport{w: writer}, nil
+	case "jsonl":
+		return &jsonlExport{w: bufio.NewWriter(w)}, nil
+	case "xlsx":
+		return &xlsxExport{zip: zip.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown export format %q (supported: %s)", format, strings.Join(exportFormatNames(), ", "))
+}
+
+// csvExport writes CSV or TSV with a header row. NULL becomes an empty field and numbers
+// are written without exponents
+type csvExport struct {
+	w    *csv.Writer
+	rows int
+}
+
+func (e *csvExport) Begin(columns []string) error {
+	return e.w.Write(columns)
+}
+
+func (e *csvExport) Row(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		if value != nil {
+			record[i] = formatValue(value)
+		}
+	}
+	if err := e.w.Write(record); err != nil {
+		return err
+	}
+	// Flush now and then so the output streams
+	if e.rows++; e.rows%flushEvery == 0 {
+		e.w.Flush()
+		return e.w.Error()
+	}
+	return nil
+}
+
+func (e *csvExport) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// jsonlExport writes one JSON object per row, with keys in column order
+type jsonlExport struct {
+	w    *bufio.Writer
+	keys [][]byte
+}
+
+func (e *jsonlExport) Begin(columns []string) error {
+	e.keys = make([][]byte, len(columns))
+	for i, column := range columns {
+		key, err := json.Marshal(column)
+		if err != nil {
+			return err
+		}
+		e.keys[i] = key
+	}
+	return nil
+}
+
+func (e *jsonlExport) Row(values []interface{}) error {
+	e.w.WriteByte('{')
+	for i, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			e.w.WriteByte(',')
+		}
+		e.w.Write(e.keys[i])
+		e.w.WriteByte(':')
+		e.w.Write(data)
+	}
+	_, err := e.w.WriteString("}\n")
+	return err
+}
+
+func (e *jsonlExport) Close() error {
+	return e.w.Flush()
+}
+
+// xlsxExport writes an Excel workbook with a single sheet. Cells use inline strings, so
+// that rows can be written as they arrive instead of collecting a shared string table
+type xlsxExport struct {
+	zip   *zip.Writer
+	sheet io.Writer
+	row   int
+}
+
+// xlsxStatic holds the workbook parts that don't depend on the data
+var xlsxStatic = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Results" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`},
+	// Cell styles: 0 general, 1 date, 2 date and time, 3 bold header
+	{"xl/styles.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="4">` +
+		`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="14" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+		`<xf numFmtId="22" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+		`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+		`</cellXfs>` +
+		`</styleSheet>`},
+}
+
+// Cell style indexes in xl/styles.xml
+const (
+	xlsxStyleDate     = 1
+	xlsxStyleDateTime = 2
+	xlsxStyleHeader   = 3
+)
+
+// excelEpoch is day zero of Excel's date serial numbers
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+func (e *xlsxExport) Begin(columns []string) error {
+	for _, part := range xlsxStatic {
+		w, err := e.zip.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, part.content); err != nil {
+			return err
+		}
+	}
+
+	// The sheet is the last part, so it can stay open while rows are added
+	sheet, err := e.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	e.sheet = sheet
+	io.WriteString(e.sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		header[i] = column
+	}
+	return e.writeRow(header, true)
+}
+
+func (e *xlsxExport) Row(values []interface{}) error {
+	return e.writeRow(values, false)
+}
+
+// writeRow writes one sheet row, typing numbers, booleans and dates
+func (e *xlsxExport) writeRow(values []interface{}, header bool) error {
+	e.row++
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, e.row)
+	for i, value := range values {
+		ref := xlsxColumn(i) + strconv.Itoa(e.row)
+		switch v := value.(type) {
+		case nil:
+			continue
+		case float64:
+			fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'g', -1, 64))
+		case int64:
+			fmt.Fprintf(&b, `<c r="%s"><v>%d</v></c>`, ref, v)
+		case bool:
+			flag := 0
+			if v {
+				flag = 1
+			}
+			fmt.Fprintf(&b, `<c r="%s" t="b"><v>%d</v></c>`, ref, flag)
+		default:
+			text := fmt.Sprint(value)
+			if !header {
+				if serial, style, ok := excelDate(text); ok {
+					fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, strconv.FormatFloat(serial, 'f', -1, 64))
+					continue
+				}
+			}
+			style := ""
+			if header {
+				style = fmt.Sprintf(` s="%d"`, xlsxStyleHeader)
+			}
+			fmt.Fprintf(&b, `<c r="%s" t="inlineStr"%s><is><t xml:space="preserve">`, ref, style)
+			xml.EscapeText(&b, []byte(text))
+			b.WriteString(`</t></is></c>`)
+		}
+	}
+	b.WriteString(`</row>`)
+	_, err := io.WriteString(e.sheet, b.String())
+	return err
+}
+
+func (e *xlsxExport) Close() error {
+	if e.sheet == nil {
+		return errors.New("no columns were written")
+	}
+	if _, err := io.WriteString(e.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	return e.zip.Close()
+}
+
+// xlsxColumn returns the spreadsheet column name for a zero-based index: A, B, ..., AA
+func xlsxColumn(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// excelDate converts the date and timestamp text produced by normalizeValue into an
+// Excel serial number and the matching cell style
+func excelDate(text string) (float64, int, bool) {
+	if len(text) == len("2006-01-02") {
+		if t, err := time.Parse("2006-01-02", text); err == nil {
+			return t.Sub(excelEpoch).Hours() / 24, xlsxStyleDate, true
+		}
+		return 0, 0, false
+	}
+	t, err := time.Parse(time.RFC3339, text)
+	if err != nil {
+		return 0, 0, false
+	}
+	// Spreadsheets have no time zones; keep the wall clock time
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	return wall.Sub(excelEpoch).Hours() / 24, xlsxStyleDateTime, true
+}
+
+// exportQuery streams the result of a custom query as a file download. The query and
+// format come from the URL or a form body, so a plain link can start an export. Exports
+// follow the read-only mode, allow-list and timeout, but not the row cap, since rows are
+// never held in memory
+func exportQuery(w http.ResponseWriter, r *http.Request, db *sql.DB, dialect string, limits QueryLimits, running *runningQueries) {
+	// Only POST, so that links and embedded images can't run queries, and the SQL stays out
+	// of access logs
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := r.FormValue("format")
+	if name == "" {
+		name = "csv"
+	}
+	format, ok := exportFormats[name]
+	if !ok {
+		writeQueryError(w, http.StatusBadRequest, "", fmt.Errorf("unknown export format %q (supported: %s)", name, strings.Join(exportFormatNames(), ", ")))
+		return
+	}
+
+	// Register the export so it can be cancelled like any other query
+	id, ctx, done, err := running.start(r.Context(), r.FormValue("id"))
+	if err != nil {
+		writeQueryError(w, http.StatusConflict, "", err)
+		return
+	}
+	defer done()
+
+	// The download starts with the first row, so that errors raised when the database starts
+	// executing the query still get an error response
+	writer, _ := newExportWriter(name, w)
+	var columns []string
+	started := false
+	start := func() error {
+		started = true
+		w.Header().Set("Content-Type", format.ContentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="results.%s"`, format.Extension))
+		return writer.Begin(columns)
+	}
+	begin := func(names []string) error {
+		columns = names
+		return nil
+	}
+	row := func(values []interface{}) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return writer.Row(values)
+	}
+
+	limits.MaxRows = 0
+	_, err = runSandboxedQuery(ctx, db, dialect, r.FormValue("query"), limits, Page{}, begin, row)
+	if err != nil && !started {
+		// The browser shows anything but an error status as the download
+		status := http.StatusBadRequest
+		if errors.Is(err, errQueryRejected) {
+			status = http.StatusForbidden
+		}
+		writeQueryError(w, status, id, err)
+		return
+	}
+	if err != nil {
+		// The download is already under way and can only be cut short
+		log.Printf("Export %s failed: %v", id, err)
+		return
+	}
+	if !started {
+		if err := start(); err != nil {
+			log.Printf("Export %s failed: %v", id, err)
+			return
+		}
+	}
+	if err := writer.Close(); err != nil {
+		log.Printf("Export %s failed: %v", id, err)
+	}
+}
+
+// exportToFile runs query and writes its result to path, or to stdout if path is empty or "-".
+// The file is written under a temporary name in the same directory and only replaces path
+// once the whole result is written, so a failing query never leaves a partial export
+func exportToFile(ctx context.Context, db *sql.DB, query, format, path string, stdout io.Writer) (err error) {
+	if _, ok := exportFormats[format]; !ok {
+		return fmt.Errorf("unknown export format %q", format)
+	}
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if path == "" || path == "-" {
+		return exportRows(rows, format, stdout)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+	if err := exportRows(rows, format, file); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// exportRows writes the rows to out in an export format
+func exportRows(rows *sql.Rows, format string, out io.Writer) error {
+	writer, err := newExportWriter(format, out)
+	if err != nil {
+		return err
+	}
+	if _, err := readRows(rows, 0, 0, writer.Begin, writer.Row); err != nil {
+		return err
+	}
+	return writer.Close()
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeExport runs a result through an export writer
+func writeExport(t *testing.T, format string, result ResultSet) []byte {
+	var buf bytes.Buffer
+	writer, err := newExportWriter(format, &buf)
+	if err != nil {
+		t.Fatalf("Failed to create %s writer: %v", format, err)
+	}
+	if err := writer.Begin(result.Columns); err != nil {
+		t.Fatalf("Failed to write columns: %v", err)
+	}
+	for _, row := range result.Rows {
+		if err := writer.Row(row); err != nil {
+			t.Fatalf("Failed to write row: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestExportWriters(t *testing.T) {
+	result := ResultSet{
+		Columns: []string{"customer", "amount", "order_date", "note"},
+		Rows: [][]interface{}{
+			{"Alice", 5000.0, "2024-03-01", nil},
+			{"Bob, Jr.", 12.5, "2024-03-05", "says \"hi\""},
+		},
+	}
+
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{"csv", "customer,amount,order_date,note\nAlice,5000,2024-03-01,\n\"Bob, Jr.\",12.5,2024-03-05,\"says \"\"hi\"\"\"\n"},
+		{"tsv", "customer\tamount\torder_date\tnote\nAlice\t5000\t2024-03-01\t\nBob, Jr.\t12.5\t2024-03-05\t\"says \"\"hi\"\"\"\n"},
+		{"jsonl", `{"customer":"Alice","amount":5000,"order_date":"2024-03-01","note":null}` + "\n" +
+			`{"customer":"Bob, Jr.","amount":12.5,"order_date":"2024-03-05","note":"says \"hi\""}` + "\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			if output := string(writeExport(t, tc.format, result)); output != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, output)
+			}
+		})
+	}
+
+	t.Run("xlsx", func(t *testing.T) {
+		data := writeExport(t, "xlsx", result)
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("Expected a zip archive, got %v", err)
+		}
+		parts := map[string]string{}
+		for _, file := range archive.File {
+			f, _ := file.Open()
+			content, _ := io.ReadAll(f)
+			f.Close()
+			parts[file.Name] = string(content)
+		}
+		for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+			if _, ok := parts[name]; !ok {
+				t.Errorf("Expected part %s in the workbook", name)
+			}
+		}
+
+		sheet := parts["xl/worksheets/sheet1.xml"]
+		for _, cell := range []string{
+			`<c r="A1" t="inlineStr" s="3"><is><t xml:space="preserve">customer</t></is></c>`,
+			`<c r="B2"><v>5000</v></c>`,
+			// 2024-03-01 is day 45352 in Excel's calendar
+			`<c r="C2" s="1"><v>45352</v></c>`,
+			`<c r="D3" t="inlineStr"><is><t xml:space="preserve">says &#34;hi&#34;</t></is></c>`,
+		} {
+			if !strings.Contains(sheet, cell) {
+				t.Errorf("Expected sheet to contain %s, got %s", cell, sheet)
+			}
+		}
+		if strings.Contains(sheet, `r="D2"`) {
+			t.Errorf("Expected NULL to leave the cell empty")
+		}
+	})
+
+	if _, err := newExportWriter("parquet", io.Discard); err == nil {
+		t.Errorf("Expected an error for an unsupported format")
+	}
+}
+
+func TestExcelDate(t *testing.T) {
+	if serial, style, ok := excelDate("2024-03-01T12:00:00+02:00"); !ok || serial != 45352.5 || style != xlsxStyleDateTime {
+		t.Errorf("Expected 45352.5 with the date and time style, got %v, %d, %v", serial, style, ok)
+	}
+	if _, _, ok := excelDate("Alice"); ok {
+		t.Errorf("Expected text not to be read as a date")
+	}
+	if column := xlsxColumn(27); column != "AB" {
+		t.Errorf("Expected column AB, got %s", column)
+	}
+}
+
+func TestExportQuery(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	running := newRunningQueries()
+
+	request := func(method string, params url.Values) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/api/export", strings.NewReader(params.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		exportQuery(rec, req, db, "sqlite", QueryLimits{ReadOnly: true, Allowed: defaultQueryLimits.Allowed, MaxRows: 2}, running)
+		return rec
+	}
+	post := func(params url.Values) *httptest.ResponseRecorder {
+		return request(http.MethodPost, params)
+	}
+
+	// The row cap of the result pages doesn't apply to exports
+	rec := post(url.Values{"query": {"SELECT customer FROM orders ORDER BY id"}, "format": {"csv"}})
+	if disposition := rec.Header().Get("Content-Disposition"); disposition != `attachment; filename="results.csv"` {
+		t.Errorf("Expected a CSV attachment, got %q", disposition)
+	}
+	if lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n"); len(lines) != 9 || lines[1] != "Alice" {
+		t.Errorf("Expected a header and 8 rows, got %q", lines)
+	}
+
+	if rec := post(url.Values{"query": {"DROP TABLE orders"}}); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d for a rejected statement, got %d", http.StatusForbidden, rec.Code)
+	}
+	if rec := post(url.Values{"query": {"SELECT 1"}, "format": {"parquet"}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unknown format, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := post(url.Values{"query": {"SELECT * FROM missing"}}); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "no such table") {
+		t.Errorf("Expected status %d with the error for a failing query, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+	if rec := request(http.MethodGet, url.Values{"query": {"SELECT 1"}}); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d for a GET request, got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+}
+
+func TestExportToFile(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	tempDir, err := os.MkdirTemp("", "export-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "totals.jsonl")
+	if err := exportToFile(context.Background(), db, "SELECT customer, SUM(amount) AS total FROM orders GROUP BY customer ORDER BY customer", "jsonl", path, io.Discard); err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), `{"customer":"Alice","total":20000}`) {
+		t.Errorf("Expected Alice's total first, got %s", data)
+	}
+
+	var stdout bytes.Buffer
+	if err := exportToFile(context.Background(), db, "SELECT COUNT(*) AS n FROM orders", "tsv", "", &stdout); err != nil || stdout.String() != "n\n8\n" {
+		t.Errorf("Expected the count on stdout, got %q (%v)", stdout.String(), err)
+	}
+
+	// Failing exports leave the previous file in place and no temporary files behind
+	failing := []struct {
+		name   string
+		query  string
+		format string
+	}{
+		{"Invalid query", "SELEC nonsense", "jsonl"},
+		{"Error after the first rows", "SELECT CASE WHEN id = 5 THEN abs(-9223372036854775808) ELSE id END AS n FROM orders ORDER BY id", "jsonl"},
+		{"Unknown format", "SELECT 1", "parquet"},
+	}
+	for _, tc := range failing {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := exportToFile(context.Background(), db, tc.query, tc.format, path, io.Discard); err == nil {
+				t.Fatalf("Expected an error")
+			}
+			if kept, _ := os.ReadFile(path); string(kept) != string(data) {
+				t.Errorf("Expected the previous export to be kept, got %s", kept)
+			}
+			if entries, _ := os.ReadDir(tempDir); len(entries) != 1 {
+				t.Errorf("Expected only the export in %s, got %d entries", tempDir, len(entries))
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// defaultSchema and defaultSeed create the sample sales data when no fixture files are given
+var (
+	//go:embed fixtures/schema.sql
+	defaultSchema string
+	//go:embed fixtures/seed.sql
+	defaultSeed string
+)
+
+// fixtureFiles holds the built-in fixtures, including the dialect variants such as
+// fixtures/schema.postgres.sql
+//
+//go:embed fixtures/*.sql
+var fixtureFiles embed.FS
+
+// Fixtures are the SQL scripts that create and populate the test database
+type Fixtures struct {
+	Schema string
+	Seed   string
+}
+
+// createTablePattern finds the tables a schema creates
+var createTablePattern = regexp.MustCompile(`(?i)\bCREATE\s+(?:TEMP\s+|TEMPORARY\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?["` + "`" + `\[]?([\w.]+)`)
+
+// loadFixtures reads the schema and seed scripts for a dialect, falling back to the
+// built-in sales data. A dialect variant next to a script, such as schema.postgres.sql
+// for schema.sql, is used instead of it when present
+func loadFixtures(schemaPath, seedPath, dialect string) (Fixtures, error) {
+	fixtures := Fixtures{
+		Schema: builtinFixture("schema", defaultSchema, dialect),
+		Seed:   builtinFixture("seed", defaultSeed, dialect),
+	}
+	if schemaPath != "" {
+		data, err := os.ReadFile(dialectPath(schemaPath, dialect))
+		if err != nil {
+			return Fixtures{}, fmt.Errorf("failed to read schema: %w", err)
+		}
+		fixtures.Schema = string(data)
+		// A custom schema doesn't fit the built-in seed data
+		fixtures.Seed = ""
+	}
+	if seedPath != "" {
+		data, err := os.ReadFile(dialectPath(seedPath, dialect))
+		if err != nil {
+			return Fixtures{}, fmt.Errorf("failed to read seed data: %w", err)
+		}
+		fixtures.Seed = string(data)
+	}
+	return fixtures, nil
+}
+
+// builtinFixture returns the embedded fixtures/<name>.<dialect>.sql, or fallback if the
+// dialect has no variant
+func builtinFixture(name, fallback, dialect string) string {
+	data, err := fixtureFiles.ReadFile(fmt.Sprintf("fixtures/%s.%s.sql", name, dialect))
+	if err != nil {
+		return fallback
+	}
+	return string(data)
+}
+
+// dialectPath returns the dialect variant of a fixture file if it exists, otherwise path
+func dialectPath(path, dialect string) string {
+	ext := filepath.Ext(path)
+	variant := strings.TrimSuffix(path, ext) + "." + dialect + ext
+	if _, err := os.Stat(variant); err == nil {
+		return variant
+	}
+	return path
+}
+
+// Tables returns the names of the tables the schema creates
+func (f Fixtures) Tables() []string {
+	var tables []string
+	for _, match := range createTablePattern.FindAllStringSubmatch(f.Schema, -1) {
+		tables = append(tables, match[1])
+	}
+	return tables
+}
+
+// applyFixtures creates the schema and inserts the seed data in one transaction.
+// With overwrite, the schema's tables are dropped first; no other table is touched
+func applyFixtures(db *sql.DB, fixtures Fixtures, overwrite bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if overwrite {
+		for _, table := range fixtures.Tables() {
+			if _, err := tx.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", table)); err != nil {
+				return fmt.Errorf("failed to drop table %s: %w", table, err)
+			}
+		}
+	}
+
+	for _, script := range []struct {
+		name string
+		sql  string
+	}{
+		{"schema", fixtures.Schema},
+		{"seed data", fixtures.Seed},
+	} {
+		for _, statement := range splitStatements(script.sql) {
+			if _, err := tx.Exec(statement); err != nil {
+				return fmt.Errorf("failed to apply %s: %w\n%s", script.name, err, statement)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit fixtures: %w", err)
+	}
+	return nil
+}
+
+// splitStatements splits a script on semicolons outside of quotes and comments.
+// Comment-only statements are dropped. Bodies containing semicolons, such as
+// CREATE TRIGGER ... BEGIN ... END, are not supported
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	hasCode := false
+
+	flush := func() {
+		statement := strings.TrimSpace(current.String())
+		if statement != "" && hasCode {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+		hasCode = false
+	}
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			// Copy the quoted text; doubled quotes are escapes and simply continue the text
+			end := i + 1
+			for end < len(script) && script[end] != c {
+				end++
+			}
+			if end == len(script) {
+				// Unterminated quote: keep the rest of the script
+				end--
+			}
+			current.WriteString(script[i : end+1])
+			hasCode = true
+			i = end
+		case c == '-' && i+1 < len(script) && script[i+1] == '-':
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i
+			}
+			current.WriteString(script[i : i+end])
+			i += end - 1
+		case c == '/' && i+1 < len(script) && script[i+1] == '*':
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				end = len(script) - i - 2
+			} else {
+				end += 2
+			}
+			current.WriteString(script[i : i+2+end])
+			i += 2 + end - 1
+		case c == ';':
+			flush()
+		default:
+			if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+				hasCode = true
+			}
+			current.WriteByte(c)
+		}
+	}
+	flush()
+	return statements
+}
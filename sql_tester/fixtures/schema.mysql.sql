@@ -0,0 +1,7 @@
+-- Schema of the sample sales database for MySQL
+CREATE TABLE orders (
+    id INTEGER AUTO_INCREMENT PRIMARY KEY,
+    customer VARCHAR(255),
+    amount DOUBLE,
+    order_date DATE
+);
@@ -0,0 +1,7 @@
+-- Schema of the sample sales database for PostgreSQL
+CREATE TABLE orders (
+    id SERIAL PRIMARY KEY,
+    customer TEXT,
+    amount DOUBLE PRECISION,
+    order_date DATE
+);
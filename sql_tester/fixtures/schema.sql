@@ -0,0 +1,7 @@
+-- Schema of the sample sales database
+CREATE TABLE orders (
+    id INTEGER PRIMARY KEY,
+    customer TEXT,
+    amount REAL,
+    order_date DATE
+);
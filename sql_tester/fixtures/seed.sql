@@ -0,0 +1,10 @@
+-- Sample orders used by the built-in suite
+INSERT INTO orders (customer, amount, order_date) VALUES
+('Alice', 5000, '2024-03-01'),
+('Bob', 8000, '2024-03-05'),
+('Alice', 3000, '2024-03-15'),
+('Charlie', 7000, '2024-02-20'),
+('Alice', 10000, '2024-02-28'),
+('Bob', 4000, '2024-02-10'),
+('Charlie', 9000, '2024-03-22'),
+('Alice', 2000, '2024-03-30');
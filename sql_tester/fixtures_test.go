@@ -0,0 +1,74 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	testCases := []struct {
+		name     string
+		script   string
+		expected []string
+	}{
+		{"Single statement", "SELECT 1", []string{"SELECT 1"}},
+		{"Several statements", "CREATE TABLE a (x);\nINSERT INTO a VALUES (1);\n", []string{"CREATE TABLE a (x)", "INSERT INTO a VALUES (1)"}},
+		{"Semicolon in string", "INSERT INTO a VALUES ('x;y');SELECT 2", []string{"INSERT INTO a VALUES ('x;y')", "SELECT 2"}},
+		{"Escaped quote", "SELECT 'it''s; fine'", []string{"SELECT 'it''s; fine'"}},
+		{"Semicolon in comments", "-- first; comment\nSELECT 1; /* block; */ SELECT 2", []string{"-- first; comment\nSELECT 1", "/* block; */ SELECT 2"}},
+		{"Comment-only statement", "SELECT 1;\n-- trailing comment\n", []string{"SELECT 1"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if statements := splitStatements(tc.script); !reflect.DeepEqual(statements, tc.expected) {
+				t.Errorf("Expected %q, got %q", tc.expected, statements)
+			}
+		})
+	}
+}
+
+func TestApplyFixtures(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "fixtures-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	db, err := sql.Open("sqlite3", filepath.Join(tempDir, "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// A table the fixtures don't know about must survive an overwrite
+	if _, err := db.Exec("CREATE TABLE keep (id INTEGER); INSERT INTO keep VALUES (1)"); err != nil {
+		t.Fatalf("Failed to create unrelated table: %v", err)
+	}
+
+	fixtures := Fixtures{Schema: defaultSchema, Seed: defaultSeed}
+	if tables := fixtures.Tables(); !reflect.DeepEqual(tables, []string{"orders"}) {
+		t.Errorf("Expected fixture tables [orders], got %v", tables)
+	}
+	if err := applyFixtures(db, fixtures, false); err != nil {
+		t.Fatalf("Failed to apply fixtures: %v", err)
+	}
+
+	// Without overwrite the existing table makes the schema fail, and nothing changes
+	if err := applyFixtures(db, fixtures, false); err == nil {
+		t.Errorf("Expected an error when the fixture tables already exist")
+	}
+	if err := applyFixtures(db, fixtures, true); err != nil {
+		t.Fatalf("Failed to overwrite fixtures: %v", err)
+	}
+
+	var orders, kept int
+	db.QueryRow("SELECT COUNT(*) FROM orders").Scan(&orders)
+	db.QueryRow("SELECT COUNT(*) FROM keep").Scan(&kept)
+	if orders != 8 || kept != 1 {
+		t.Errorf("Expected 8 orders and 1 kept row, got %d and %d", orders, kept)
+	}
+}
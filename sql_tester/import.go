@@ -0,0 +1,627 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxUploadSize limits the files accepted by /api/import
+const maxUploadSize = 32 << 20
+
+// maxImportSize limits the files read by importData, which holds every row in memory to
+// infer the column types (variable for testing)
+var maxImportSize int64 = 256 << 20
+
+// errImportTooLarge is returned for files larger than maxImportSize
+var errImportTooLarge = errors.New("the file exceeds the import size limit")
+
+// sizeLimitedReader fails with errImportTooLarge once more than remaining bytes are read,
+// rather than silently cutting the file short like io.LimitReader
+type sizeLimitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+// Read reads from the underlying reader within the limit
+func (l *sizeLimitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, errImportTooLarge
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, fmt.Errorf("%w of %d MB", errImportTooLarge, maxImportSize>>20)
+	}
+	return n, err
+}
+
+// maxInsertParams keeps multi-row INSERT statements below SQLite's oldest limit on
+// bound parameters
+const maxInsertParams = 999
+
+// ImportOptions control how a data file is read and inserted
+type ImportOptions struct {
+	// Table receives the rows; it is created from the inferred column types if it doesn't exist
+	Table string
+	// Format is "csv" or "json" (an array of objects, or one object per line)
+	Format string
+	// Header reads the column names from the first CSV row; otherwise columns are named column1, column2, ...
+	Header bool
+	// Delimiter separates CSV fields
+	Delimiter rune
+	// BatchSize is the number of rows per INSERT statement
+	BatchSize int
+}
+
+// defaultImportOptions are used for options left unset
+var defaultImportOptions = ImportOptions{Format: "csv", Header: true, Delimiter: ',', BatchSize: 500}
+
+// ImportResult summarizes an import
+type ImportResult struct {
+	Table   string   `json:"table"`
+	Created bool     `json:"created"`
+	Columns []string `json:"columns"`
+	Types   []string `json:"types"`
+	Rows    int      `json:"rows"`
+}
+
+// Column types inferred from the data, in order of preference
+const (
+	typeInteger   = "INTEGER"
+	typeReal      = "REAL"
+	typeBoolean   = "BOOLEAN"
+	typeDate      = "DATE"
+	typeTimestamp = "TIMESTAMP"
+	typeText      = "TEXT"
+)
+
+// dialectTypes maps inferred types to column types where a dialect's name differs
+var dialectTypes = map[string]map[string]string{
+	"postgres": {typeInteger: "BIGINT", typeReal: "DOUBLE PRECISION"},
+	"mysql":    {typeInteger: "BIGINT", typeReal: "DOUBLE", typeTimestamp: "DATETIME"},
+}
+
+// timestampLayouts are the timestamp formats recognised in data files
+var timestampLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05"}
+
+// importFormat picks the format from the options or the file name
+func importFormat(format, filename string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".json", ".jsonl", ".ndjson":
+			format = "json"
+		default:
+			format = "csv"
+		}
+	}
+	if format != "csv" && format != "json" {
+		return "", fmt.Errorf("unknown import format %q (supported: csv, json)", format)
+	}
+	return format, nil
+}
+
+// tableName derives a table name from a file name, as in "sales-2024.csv" -> "sales_2024"
+func tableName(filename string) string {
+	base := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	name := strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, base)
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "t_" + name
+	}
+	return name
+}
+
+// readImportFile reads the columns and rows of a CSV or JSON file. Values stay text until
+// their column's type is known; missing values are nil
+func readImportFile(r io.Reader, options ImportOptions) ([]string, [][]interface{}, error) {
+	if options.Format == "json" {
+		return readJSONRecords(r)
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = options.Delimiter
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil, errors.New("the file is empty")
+	}
+
+	var columns []string
+	if options.Header {
+		columns = records[0]
+		records = records[1:]
+	} else {
+		for i := range records[0] {
+			columns = append(columns, fmt.Sprintf("column%d", i+1))
+		}
+	}
+
+	rows := make([][]interface{}, len(records))
+	for i, record := range records {
+		if len(record) != len(columns) {
+			return nil, nil, fmt.Errorf("row %d has %d fields, expected %d", i+1, len(record), len(columns))
+		}
+		row := make([]interface{}, len(record))
+		for j, field := range record {
+			// CSV has no NULL; an empty field is the closest thing
+			if field != "" {
+				row[j] = field
+			}
+		}
+		rows[i] = row
+	}
+	return columns, rows, nil
+}
+
+// readJSONRecords reads an array of objects, or a sequence of objects such as JSON lines.
+// Columns are taken in the order their keys first appear
+func readJSONRecords(r io.Reader) ([]string, [][]interface{}, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	var columns []string
+	index := map[string]int{}
+	var objects []map[string]interface{}
+
+	// readObject reads the key-value pairs of one object, keeping the key order
+	readObject := func() error {
+		object := map[string]interface{}{}
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			key := token.(string)
+			var value interface{}
+			if err := decoder.Decode(&value); err != nil {
+				return err
+			}
+			if _, ok := index[key]; !ok {
+				index[key] = len(columns)
+				columns = append(columns, key)
+			}
+			object[key] = value
+		}
+		objects = append(objects, object)
+		_, err := decoder.Token() // closing brace
+		return err
+	}
+
+	inArray := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read JSON: %w", err)
+		}
+		switch token {
+		case json.Delim('['):
+			if inArray || len(objects) > 0 {
+				return nil, nil, errors.New("failed to read JSON: expected an array of objects, got an array inside it")
+			}
+			inArray = true
+		case json.Delim(']'):
+			if !inArray {
+				return nil, nil, errors.New("failed to read JSON: expected an array of objects, got an unmatched ]")
+			}
+			inArray = false
+		case json.Delim('{'):
+			if err := readObject(); err != nil {
+				return nil, nil, fmt.Errorf("failed to read JSON: %w", err)
+			}
+		default:
+			return nil, nil, fmt.Errorf("failed to read JSON: expected an array of objects, or one object per line, got %v", token)
+		}
+	}
+	if len(objects) == 0 {
+		return nil, nil, errors.New("the file holds no objects")
+	}
+
+	rows := make([][]interface{}, len(objects))
+	for i, object := range objects {
+		row := make([]interface{}, len(columns))
+		for key, value := range object {
+			switch v := value.(type) {
+			case nil:
+			case json.Number:
+				row[index[key]] = v.String()
+			case string:
+				row[index[key]] = v
+			case bool:
+				row[index[key]] = strconv.FormatBool(v)
+			default:
+				// Nested objects and arrays are stored as JSON text
+				data, _ := json.Marshal(v)
+				row[index[key]] = string(data)
+			}
+		}
+		rows[i] = row
+	}
+	return columns, rows, nil
+}
+
+// inferTypes picks the narrowest type that fits every non-null value of each column
+func inferTypes(columns []string, rows [][]interface{}) []string {
+	types := make([]string, len(columns))
+	for i := range columns {
+		candidates := map[string]bool{typeInteger: true, typeReal: true, typeBoolean: true, typeDate: true, typeTimestamp: true}
+		seen := false
+		for _, row := range rows {
+			text, ok := row[i].(string)
+			if !ok {
+				continue
+			}
+			seen = true
+			text = strings.TrimSpace(text)
+			// Numbers with leading zeros, such as zip codes, would lose them as numbers, and
+			// integers beyond int64 would lose digits as REAL
+			padded := hasLeadingZero(text)
+			_, err := strconv.ParseInt(text, 10, 64)
+			if err != nil || padded {
+				candidates[typeInteger] = false
+			}
+			tooBig := errors.Is(err, strconv.ErrRange)
+			if f, err := strconv.ParseFloat(text, 64); err != nil || padded || tooBig || math.IsNaN(f) || math.IsInf(f, 0) {
+				candidates[typeReal] = false
+			}
+			if lower := strings.ToLower(text); lower != "true" && lower != "false" {
+				candidates[typeBoolean] = false
+			}
+			if _, err := time.Parse("2006-01-02", text); err != nil {
+				candidates[typeDate] = false
+			}
+			if _, ok := parseTimestamp(text); !ok {
+				candidates[typeTimestamp] = false
+			}
+		}
+
+		types[i] = typeText
+		if !seen {
+			continue
+		}
+		for _, candidate := range []string{typeInteger, typeReal, typeBoolean, typeDate, typeTimestamp} {
+			if candidates[candidate] {
+				types[i] = candidate
+				break
+			}
+		}
+	}
+	return types
+}
+
+// hasLeadingZero reports whether a number is written with a leading zero, as in "01234"
+func hasLeadingZero(text string) bool {
+	text = strings.TrimLeft(text, "+-")
+	return len(text) > 1 && text[0] == '0' && text[1] >= '0' && text[1] <= '9'
+}
+
+// parseTimestamp reads a timestamp in one of the recognised layouts
+func parseTimestamp(text string) (time.Time, bool) {
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// convertValue turns text into the Go value for a column type
+func convertValue(value interface{}, columnType string) interface{} {
+	text, ok := value.(string)
+	if !ok {
+		return value
+	}
+	trimmed := strings.TrimSpace(text)
+	switch columnType {
+	case typeInteger:
+		if n, err := strconv.ParseInt(trimmed, 10, 64); err == nil {
+			return n
+		}
+	case typeReal:
+		if f, err := strconv.ParseFloat(trimmed, 64); err == nil {
+			return f
+		}
+	case typeBoolean:
+		if b, err := strconv.ParseBool(strings.ToLower(trimmed)); err == nil {
+			return b
+		}
+	case typeTimestamp:
+		if t, ok := parseTimestamp(trimmed); ok {
+			return t
+		}
+	}
+	return text
+}
+
+// quoteIdentifier quotes a table or column name for a dialect
+func quoteIdentifier(dialect, name string) string {
+	if dialect == "mysql" {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// placeholder returns the n-th (1-based) bind parameter for a dialect
+func placeholder(dialect string, n int) string {
+	if dialect == "postgres" {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// importData reads a CSV or JSON file and inserts its rows into options.Table in a single
+// transaction, creating the table from the inferred column types if needed. Nothing is
+// inserted if any row fails
+func importData(ctx context.Context, db *sql.DB, dialect string, r io.Reader, options ImportOptions) (ImportResult, error) {
+	if options.Table == "" {
+		return ImportResult{}, errors.New("no table name given")
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaultImportOptions.BatchSize
+	}
+	if options.Delimiter == 0 {
+		options.Delimiter = defaultImportOptions.Delimiter
+	}
+
+	columns, rows, err := readImportFile(&sizeLimitedReader{r: r, remaining: maxImportSize}, options)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	for i, column := range columns {
+		if strings.TrimSpace(column) == "" {
+			return ImportResult{}, fmt.Errorf("column %d has no name", i+1)
+		}
+	}
+	result := ImportResult{Table: options.Table, Columns: columns, Types: inferTypes(columns, rows)}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The table is looked up and created within the transaction, so that concurrent imports
+	// into a new table don't both try to create it
+	tables, err := listTables(tx, dialect)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	result.Created = len(commonTables(tables, []string{options.Table})) == 0
+
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteIdentifier(dialect, column)
+	}
+	table := quoteIdentifier(dialect, options.Table)
+
+	if result.Created {
+		definitions := make([]string, len(columns))
+		for i := range columns {
+			columnType := result.Types[i]
+			if mapped, ok := dialectTypes[dialect][columnType]; ok {
+				columnType = mapped
+			}
+			definitions[i] = quoted[i] + " " + columnType
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", table, strings.Join(definitions, ", "))); err != nil {
+			return ImportResult{}, fmt.Errorf("failed to create table %s: %w", options.Table, err)
+		}
+	}
+
+	// Insert several rows per statement, within the bind parameter limit
+	perStatement := options.BatchSize
+	if perStatement*len(columns) > maxInsertParams {
+		perStatement = maxInsertParams / len(columns)
+		if perStatement == 0 {
+			perStatement = 1
+		}
+	}
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES ", table, strings.Join(quoted, ", "))
+	for start := 0; start < len(rows); start += perStatement {
+		end := start + perStatement
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		var statement strings.Builder
+		statement.WriteString(prefix)
+		args := make([]interface{}, 0, (end-start)*len(columns))
+		for i, row := range rows[start:end] {
+			if i > 0 {
+				statement.WriteString(", ")
+			}
+			statement.WriteByte('(')
+			for j, value := range row {
+				if j > 0 {
+					statement.WriteString(", ")
+				}
+				args = append(args, convertValue(value, result.Types[j]))
+				statement.WriteString(placeholder(dialect, len(args)))
+			}
+			statement.WriteByte(')')
+		}
+		if _, err := tx.ExecContext(ctx, statement.String(), args...); err != nil {
+			return ImportResult{}, fmt.Errorf("failed to insert rows %d-%d: %w", start+1, end, err)
+		}
+		result.Rows = end
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ImportResult{}, fmt.Errorf("failed to commit import: %w", err)
+	}
+	return result, nil
+}
+
+// parseDelimiter reads a single-character delimiter; "\t" and "tab" mean a tab
+func parseDelimiter(text string) (rune, error) {
+	switch text {
+	case "":
+		return defaultImportOptions.Delimiter, nil
+	case `\t`, "tab":
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(text)
+	if size != len(text) || r == utf8.RuneError || r == '"' || r == '\n' || r == '\r' {
+		return 0, fmt.Errorf("invalid delimiter %q: use a single character other than a quote or line break", text)
+	}
+	return r, nil
+}
+
+// printImportResult displays an import summary
+func printImportResult(result ImportResult) {
+	action := "Imported"
+	if result.Created {
+		action = "Created table and imported"
+	}
+	fmt.Printf("✅ %s %d row(s) into %s\n", action, result.Rows, result.Table)
+	for i, column := range result.Columns {
+		fmt.Printf("  %s %s\n", column, result.Types[i])
+	}
+}
+
+// runImportCommand implements "sql_tester import [flags] file"
+func runImportCommand(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	dbPath := flags.String("db", "", "Path to the SQLite database to import into; created if it doesn't exist")
+	driver := flags.String("driver", "sqlite3", "Database driver: sqlite3, postgres or mysql")
+	dsn := flags.String("dsn", "", "Connection string for the postgres and mysql drivers")
+	table := flags.String("table", "", "Table to import into (default: derived from the file name)")
+	format := flags.String("format", "", "File format: csv or json (default: from the file extension)")
+	header := flags.Bool("header", defaultImportOptions.Header, "The first CSV row holds the column names")
+	delimiter := flags.String("delimiter", ",", `CSV field delimiter; "\t" or "tab" for tabs`)
+	batch := flags.Int("batch", defaultImportOptions.BatchSize, "Rows per INSERT statement")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: sql_tester import [flags] file")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("import needs exactly one file")
+	}
+	path := flags.Arg(0)
+
+	engine := Engine{Name: dialects[*driver], Driver: *driver, DSN: *dsn}
+	switch {
+	case engine.Dialect() == "":
+		return fmt.Errorf("unknown driver %q (supported: sqlite3, postgres, mysql)", *driver)
+	case engine.Dialect() == "sqlite" && *dsn == "":
+		if *dbPath == "" {
+			return errors.New("import needs a database given with -db")
+		}
+		engine.DSN = *dbPath
+	case engine.Dialect() != "sqlite" && *dsn == "":
+		return fmt.Errorf("the %s driver needs a connection string given with -dsn", *driver)
+	}
+
+	options := ImportOptions{Table: *table, Header: *header, BatchSize: *batch}
+	var err error
+	if options.Format, err = importFormat(*format, path); err != nil {
+		return err
+	}
+	if options.Delimiter, err = parseDelimiter(*delimiter); err != nil {
+		return err
+	}
+	if options.Table == "" {
+		options.Table = tableName(path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	db, err := sql.Open(engine.Driver, engine.DSN)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	result, err := importData(context.Background(), db, engine.Dialect(), file, options)
+	if err != nil {
+		return err
+	}
+	printImportResult(result)
+	return nil
+}
+
+// importUpload loads an uploaded CSV or JSON file into a table. The multipart form has the
+// file in "file" and optional "table", "format", "header", "delimiter" and "batch" fields.
+// Uploads are refused unless the server was started with -web-import
+func importUpload(w http.ResponseWriter, r *http.Request, db *sql.DB, dialect string, limits QueryLimits) {
+	// Only accept POST requests
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !limits.AllowImport {
+		writeQueryError(w, http.StatusForbidden, "", errors.New("imports are disabled; start the server with -web-import to allow them"))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	file, fileHeader, err := r.FormFile("file")
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.As(err, new(*http.MaxBytesError)) {
+			status = http.StatusRequestEntityTooLarge
+			err = fmt.Errorf("the upload exceeds the limit of %d MB", maxUploadSize>>20)
+		}
+		writeQueryError(w, status, "", fmt.Errorf("error reading upload: %w", err))
+		return
+	}
+	defer file.Close()
+
+	options := ImportOptions{Table: r.FormValue("table"), Header: r.FormValue("header") != "false", BatchSize: defaultImportOptions.BatchSize}
+	if options.Format, err = importFormat(r.FormValue("format"), fileHeader.Filename); err == nil {
+		options.Delimiter, err = parseDelimiter(r.FormValue("delimiter"))
+	}
+	if err == nil && r.FormValue("batch") != "" {
+		if options.BatchSize, err = strconv.Atoi(r.FormValue("batch")); err != nil {
+			err = fmt.Errorf("invalid batch size %q", r.FormValue("batch"))
+		}
+	}
+	if err != nil {
+		writeQueryError(w, http.StatusBadRequest, "", err)
+		return
+	}
+	if options.Table == "" {
+		options.Table = tableName(fileHeader.Filename)
+	}
+
+	result, err := importData(r.Context(), db, dialect, file, options)
+	if err != nil {
+		writeQueryError(w, http.StatusBadRequest, "", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
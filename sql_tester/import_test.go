@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInferTypes(t *testing.T) {
+	columns := []string{"id", "price", "active", "day", "at", "name", "empty", "zip", "code", "ratio", "limit", "serial"}
+	rows := [][]interface{}{
+		{"1", "2.5", "true", "2024-03-01", "2024-03-01 10:00:00", "Alice", nil, "01234", "0", "0.5", "1.5", "1"},
+		{"2", "3", "FALSE", "2024-03-02", "2024-03-02T11:30:00Z", "42", nil, "98101", "007", "-0.25", "Inf", "18446744073709551615"},
+		{nil, nil, nil, nil, nil, nil, nil, nil, nil, "NaN", "infinity", "-9223372036854775809"},
+	}
+	expected := []string{typeInteger, typeReal, typeBoolean, typeDate, typeTimestamp, typeText, typeText, typeText, typeText, typeText, typeText, typeText}
+	if types := inferTypes(columns, rows); !reflect.DeepEqual(types, expected) {
+		t.Errorf("Expected %v, got %v", expected, types)
+	}
+}
+
+func TestImportData(t *testing.T) {
+	testCases := []struct {
+		name            string
+		data            string
+		options         ImportOptions
+		expectedTable   string
+		expectedCreated bool
+		expectedColumns []string
+		expectedTypes   []string
+		expectedRows    int
+	}{
+		{
+			"CSV with header into a new table",
+			"sku,price,in stock\nA-1,9.99,true\nB-2,15,false\nC-3,,true\n",
+			ImportOptions{Table: "products", Format: "csv", Header: true, BatchSize: 2},
+			"products", true, []string{"sku", "price", "in stock"}, []string{typeText, typeReal, typeBoolean}, 3,
+		},
+		{
+			"Semicolon CSV without header",
+			"1;Alice\n2;Bob\n",
+			ImportOptions{Table: "people", Format: "csv", Delimiter: ';'},
+			"people", true, []string{"column1", "column2"}, []string{typeInteger, typeText}, 2,
+		},
+		{
+			"JSON array into the existing orders table",
+			`[{"customer": "Dora", "amount": 10.5, "order_date": "2024-04-01"}, {"customer": "Eve", "amount": 20, "order_date": null}]`,
+			ImportOptions{Table: "orders", Format: "json"},
+			"orders", false, []string{"customer", "amount", "order_date"}, []string{typeText, typeReal, typeDate}, 2,
+		},
+		{
+			"JSON lines with nested values",
+			"{\"id\": 1, \"tags\": [\"a\", \"b\"]}\n{\"id\": 2, \"extra\": {\"x\": 1}}\n",
+			ImportOptions{Table: "events", Format: "json", BatchSize: 1},
+			"events", true, []string{"id", "tags", "extra"}, []string{typeInteger, typeText, typeText}, 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := openTestDB(t)
+			defer db.Close()
+
+			var before int
+			if !tc.expectedCreated {
+				db.QueryRow("SELECT COUNT(*) FROM " + tc.expectedTable).Scan(&before)
+			}
+			result, err := importData(context.Background(), db, "sqlite", strings.NewReader(tc.data), tc.options)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if result.Table != tc.expectedTable || result.Created != tc.expectedCreated || result.Rows != tc.expectedRows {
+				t.Errorf("Expected %d rows into %s (created %v), got %d into %s (created %v)",
+					tc.expectedRows, tc.expectedTable, tc.expectedCreated, result.Rows, result.Table, result.Created)
+			}
+			if !reflect.DeepEqual(result.Columns, tc.expectedColumns) || !reflect.DeepEqual(result.Types, tc.expectedTypes) {
+				t.Errorf("Expected columns %v %v, got %v %v", tc.expectedColumns, tc.expectedTypes, result.Columns, result.Types)
+			}
+
+			var count int
+			if err := db.QueryRow(`SELECT COUNT(*) FROM "` + tc.expectedTable + `"`).Scan(&count); err != nil {
+				t.Fatalf("Failed to count rows: %v", err)
+			}
+			if count != before+tc.expectedRows {
+				t.Errorf("Expected %d rows in %s, got %d", before+tc.expectedRows, tc.expectedTable, count)
+			}
+		})
+	}
+}
+
+func TestImportDataValues(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	data := "sku,price,in stock\nA-1,9.99,true\nC-3,,false\n"
+	if _, err := importData(context.Background(), db, "sqlite", strings.NewReader(data), ImportOptions{Table: "products", Format: "csv", Header: true}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	result, err := runQuery(context.Background(), db, `SELECT sku, price, "in stock" FROM products ORDER BY sku`)
+	if err != nil {
+		t.Fatalf("Failed to query imported rows: %v", err)
+	}
+	expected := [][]interface{}{{"A-1", 9.99, true}, {"C-3", nil, false}}
+	if !reflect.DeepEqual(result.Rows, expected) {
+		t.Errorf("Expected %v, got %v", expected, result.Rows)
+	}
+}
+
+func TestImportDataConcurrently(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	// Both imports queue up for the only connection, which the pool hands to them in turn;
+	// each must still find out whether the table exists within its own transaction
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get a connection: %v", err)
+	}
+	results := make(chan ImportResult, 2)
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			result, err := importData(context.Background(), db, "sqlite", strings.NewReader("sku\nA-1\n"), ImportOptions{Table: "skus", Format: "csv", Header: true})
+			results <- result
+			errs <- err
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	conn.Close()
+	created := 0
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+		if (<-results).Created {
+			created++
+		}
+	}
+	if created != 1 {
+		t.Errorf("Expected exactly one import to create the table, got %d", created)
+	}
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM skus").Scan(&count)
+	if count != 2 {
+		t.Errorf("Expected 2 rows, got %d", count)
+	}
+}
+
+func TestImportDataRollsBack(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	// The second batch fails on the primary key, so the first must not be kept either
+	data := "id,customer\n100,Zed\n101,Zoe\n1,Duplicate\n"
+	_, err := importData(context.Background(), db, "sqlite", strings.NewReader(data), ImportOptions{Table: "orders", Format: "csv", Header: true, BatchSize: 2})
+	if err == nil || !strings.Contains(err.Error(), "rows 3-3") {
+		t.Fatalf("Expected an insert error for row 3, got %v", err)
+	}
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM orders").Scan(&count)
+	if count != 8 {
+		t.Errorf("Expected 8 orders after the failed import, got %d", count)
+	}
+
+	testCases := []struct {
+		name        string
+		data        string
+		options     ImportOptions
+		expectError string
+	}{
+		{"Ragged CSV", "a,b\n1\n", ImportOptions{Table: "t", Format: "csv", Header: true}, "row 1 has 1 fields"},
+		{"Empty CSV", "", ImportOptions{Table: "t", Format: "csv"}, "empty"},
+		{"JSON scalars", "[1, 2]", ImportOptions{Table: "t", Format: "json"}, "expected an array of objects"},
+		{"Nested JSON arrays", "[[{\"a\": 1}]]", ImportOptions{Table: "t", Format: "json"}, "expected an array of objects"},
+		{"Top-level JSON string", `"text"`, ImportOptions{Table: "t", Format: "json"}, "expected an array of objects"},
+		{"No table", "a\n1\n", ImportOptions{Format: "csv"}, "no table name"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := importData(context.Background(), db, "sqlite", strings.NewReader(tc.data), tc.options)
+			if err == nil || !strings.Contains(err.Error(), tc.expectError) {
+				t.Errorf("Expected error containing %q, got %v", tc.expectError, err)
+			}
+		})
+	}
+
+	// Files over the size limit fail instead of being cut short
+	defer func(limit int64) { maxImportSize = limit }(maxImportSize)
+	maxImportSize = 16
+	_, err = importData(context.Background(), db, "sqlite", strings.NewReader("a\n"+strings.Repeat("1\n", 8)), ImportOptions{Table: "t", Format: "csv"})
+	if !errors.Is(err, errImportTooLarge) {
+		t.Errorf("Expected the size limit error, got %v", err)
+	}
+	if _, err := importData(context.Background(), db, "sqlite", strings.NewReader("a\n"+strings.Repeat("1\n", 7)), ImportOptions{Table: "t", Format: "csv", Header: true}); err != nil {
+		t.Errorf("Expected a file of exactly the limit to import, got %v", err)
+	}
+}
+
+func TestImportHelpers(t *testing.T) {
+	names := map[string]string{"data/sales-2024.csv": "sales_2024", "2024.json": "t_2024", "orders.jsonl": "orders"}
+	for filename, expected := range names {
+		if name := tableName(filename); name != expected {
+			t.Errorf("Expected table name %q for %s, got %q", expected, filename, name)
+		}
+	}
+
+	formats := map[string]string{"a.csv": "csv", "a.JSON": "json", "a.ndjson": "json", "a.txt": "csv"}
+	for filename, expected := range formats {
+		if format, err := importFormat("", filename); err != nil || format != expected {
+			t.Errorf("Expected format %q for %s, got %q (%v)", expected, filename, format, err)
+		}
+	}
+	if _, err := importFormat("xml", "a.xml"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+
+	delimiters := map[string]rune{"": ',', ";": ';', `\t`: '\t', "tab": '\t', "|": '|'}
+	for text, expected := range delimiters {
+		if delimiter, err := parseDelimiter(text); err != nil || delimiter != expected {
+			t.Errorf("Expected delimiter %q for %q, got %q (%v)", expected, text, delimiter, err)
+		}
+	}
+	for _, text := range []string{`"`, ";;", "\n"} {
+		if _, err := parseDelimiter(text); err == nil {
+			t.Errorf("Expected an error for delimiter %q", text)
+		}
+	}
+
+	if quoted := quoteIdentifier("sqlite", `in "stock"`); quoted != `"in ""stock"""` {
+		t.Errorf("Expected a quoted identifier, got %s", quoted)
+	}
+	if quoted := quoteIdentifier("mysql", "in stock"); quoted != "`in stock`" {
+		t.Errorf("Expected a backquoted identifier, got %s", quoted)
+	}
+	if p := placeholder("postgres", 3); p != "$3" {
+		t.Errorf("Expected $3, got %s", p)
+	}
+}
+
+// uploadRequest builds a multipart request uploading data as filename
+func uploadRequest(t *testing.T, filename, data string, fields map[string]string) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	part.Write([]byte(data))
+	for name, value := range fields {
+		writer.WriteField(name, value)
+	}
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/import", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestImportUpload(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	// Imports are off by default
+	rec := httptest.NewRecorder()
+	importUpload(rec, uploadRequest(t, "products.csv", "sku\nA-1\n", nil), db, "sqlite", defaultQueryLimits)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d", http.StatusForbidden, rec.Code)
+	}
+
+	limits := defaultQueryLimits
+	limits.AllowImport = true
+	rec = httptest.NewRecorder()
+	importUpload(rec, uploadRequest(t, "product list.tsv", "A-1\t9.99\nB-2\t15\n", map[string]string{"delimiter": "tab", "header": "false"}), db, "sqlite", limits)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var result ImportResult
+	json.NewDecoder(rec.Body).Decode(&result)
+	if result.Table != "product_list" || !result.Created || result.Rows != 2 || !reflect.DeepEqual(result.Types, []string{typeText, typeReal}) {
+		t.Errorf("Expected 2 rows in a new product_list table, got %+v", result)
+	}
+
+	rec = httptest.NewRecorder()
+	importUpload(rec, uploadRequest(t, "big.csv", strings.Repeat("x", maxUploadSize), nil), db, "sqlite", limits)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status %d for an oversized upload, got %d: %s", http.StatusRequestEntityTooLarge, rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	importUpload(rec, uploadRequest(t, "orders.json", `[{"customer": "Dora"}]`, map[string]string{"batch": "many"}), db, "sqlite", limits)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid batch size") {
+		t.Errorf("Expected a bad request for the batch size, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
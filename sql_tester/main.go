@@ -1,119 +1,188 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	_ "github.com/mattn/go-sqlite3" // SQLite driver
 )
 
-// Query represents a SQL query with its expected result
+// Query represents a SQL query with its expected result set
 type Query struct {
-	Name        string
-	Description string
-	SQL         string
-	Expected    float64
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	SQL         string       `json:"sql"`
+	Expected    *Expectation `json:"expected,omitempty"`
+	// Setup runs before the SQL, inside the test's transaction
+	Setup []string `json:"setup,omitempty"`
+	// Teardown runs after the transaction is rolled back, to clean up what it can't undo
+	Teardown []string `json:"teardown,omitempty"`
+	// ExpectAffected is the number of rows the test's DML statements must change
+	ExpectAffected *int64 `json:"expect_affected,omitempty"`
 }
 
 func main() {
+	// "import" loads a data file instead of running the suite
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		if err := runImportCommand(os.Args[2:]); err != nil {
+			log.Fatalf("Error importing data: %v", err)
+		}
+		return
+	}
+
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run runs the tester as configured by the command line flags. Errors are returned rather
+// than fatal, so that deferred cleanups run
+func run() error {
 	// Parse command line flags
-	dbPath := flag.String("db", "sales.db", "Path to the SQLite database")
+	dbPath := flag.String("db", "", "Path to the SQLite database (default: a temporary database removed on exit)")
+	driver := flag.String("driver", "sqlite3", "Database driver: sqlite3, postgres or mysql")
+	dsn := flag.String("dsn", "", "Connection string for the postgres and mysql drivers")
+	var extraEngines engineList
+	flag.Var(&extraEngines, "engine", "Additional engine to run the suite on and compare with, as driver=dsn (repeatable)")
+	overwrite := flag.Bool("overwrite", false, "Allow recreating the fixture tables in an existing database")
+	noFixtures := flag.Bool("no-fixtures", false, "Use the database as is, without applying fixtures")
+	schemaPath := flag.String("schema", "", "SQL file creating the schema (default: built-in sales schema)")
+	seedPath := flag.String("seed", "", "SQL file inserting the seed data (default: built-in sales data)")
 	webMode := flag.Bool("web", false, "Run in web server mode")
 	webPort := flag.Int("port", 8080, "Port for web server mode")
+	webReadOnly := flag.Bool("web-read-only", defaultQueryLimits.ReadOnly, "Run custom web queries read-only and roll them back")
+	webAllow := flag.String("web-allow", strings.Join(defaultQueryLimits.Allowed, ","), "Comma-separated statement keywords custom web queries may start with (empty allows any)")
+	webTimeout := flag.Duration("web-timeout", defaultQueryLimits.Timeout, "Timeout for custom web queries (0 for none)")
+	webMaxRows := flag.Int("web-max-rows", defaultQueryLimits.MaxRows, "Maximum rows returned by a custom web query (0 for no limit)")
+	webImport := flag.Bool("web-import", false, "Allow uploading CSV and JSON files into the database through /api/import")
+	suitePath := flag.String("suite", "", "SQL suite file or directory of .sql files (default: built-in sales suite)")
+	exportSQL := flag.String("query", "", "Run this query instead of the suite and export its result")
+	exportFormat := flag.String("format", "csv", "Export format for -query: "+strings.Join(exportFormatNames(), ", "))
+	exportPath := flag.String("output", "", "File to export the -query result to (default: stdout)")
 	flag.Parse()
 
-	fmt.Println("SQL Tester - Sales Data Analysis")
-	fmt.Println("================================")
-	fmt.Println()
-
-	// Create or open the database
-	db, err := sql.Open("sqlite3", *dbPath)
+	// Load the tests before touching the database so suite errors are reported early
+	queries, err := loadSuite(*suitePath)
 	if err != nil {
-		log.Fatalf("Error opening database: %v", err)
+		return fmt.Errorf("Error loading suite: %w", err)
 	}
-	defer db.Close()
 
-	// Create and populate the database
-	if err := setupDatabase(db); err != nil {
-		log.Fatalf("Error setting up database: %v", err)
+	// The main engine comes from -driver with -dsn, or -db for SQLite
+	primary := Engine{Name: dialects[*driver], Driver: *driver, DSN: *dsn}
+	switch {
+	case primary.Dialect() == "":
+		return fmt.Errorf("Unknown driver %q (supported: sqlite3, postgres, mysql)", *driver)
+	case primary.Dialect() == "sqlite" && *dbPath != "":
+		if *dsn != "" {
+			return errors.New("-db and -dsn both name the SQLite database; pass only one")
+		}
+		primary.DSN = *dbPath
+	case primary.Dialect() != "sqlite" && *dbPath != "":
+		return fmt.Errorf("-db only applies to the sqlite3 driver; pass -dsn for %s", *driver)
+	case primary.Dialect() != "sqlite" && *dsn == "":
+		return fmt.Errorf("The %s driver needs a connection string given with -dsn", *driver)
 	}
-	fmt.Println("✅ Database created and populated with sample data")
-	fmt.Println()
-
-	// Check if web mode is enabled
-	if *webMode {
-		fmt.Printf("Starting web interface on port %d...\n", *webPort)
-		runWebServer(db, *webPort)
-		return
+	engines := uniqueNames(append([]Engine{primary}, extraEngines...))
+	if *webMode && len(engines) > 1 {
+		return errors.New("-engine compares engines in CLI mode and can't be combined with -web")
 	}
-
-	// Define the queries to test
-	queries := []Query{
-		{
-			Name:        "Total Sales for March 2024",
-			Description: "Calculate the total sales volume for March 2024",
-			SQL:         "SELECT SUM(amount) FROM orders WHERE order_date BETWEEN '2024-03-01' AND '2024-03-31'",
-			Expected:    27000.0,
-		},
-		{
-			Name:        "Top-spending Customer",
-			Description: "Find the customer who spent the most overall",
-			SQL: `
-				SELECT customer, SUM(amount) AS total_spent 
-				FROM orders 
-				GROUP BY customer 
-				ORDER BY total_spent DESC 
-				LIMIT 1
-			`,
-			Expected: 20000.0,
-		},
-		{
-			Name:        "Average Order Value",
-			Description: "Calculate the average order value for all orders",
-			SQL:         "SELECT AVG(amount) FROM orders",
-			Expected:    6000.0,
-		},
+	exporting := *exportSQL != ""
+	if exporting {
+		if *webMode || len(engines) > 1 {
+			return errors.New("-query exports from a single engine and can't be combined with -web or -engine")
+		}
+		if _, ok := exportFormats[*exportFormat]; !ok {
+			return fmt.Errorf("Unknown export format %q (supported: %s)", *exportFormat, strings.Join(exportFormatNames(), ", "))
+		}
 	}
 
-	// Run the queries and check results
-	for _, q := range queries {
-		fmt.Printf("Test: %s\n", q.Name)
-		fmt.Printf("Description: %s\n", q.Description)
-		fmt.Printf("SQL Query: %s\n", q.SQL)
+	// The export may go to stdout, so it gets no banner or progress messages
+	if !exporting {
+		fmt.Println("SQL Tester - Sales Data Analysis")
+		fmt.Println("================================")
+		fmt.Println()
+	}
 
-		var result float64
-		var customer string
+	// Stop on Ctrl+C or SIGTERM through the normal return path, so the deferred cleanups,
+	// such as removing the temporary database, still run
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Connect to every engine and apply the fixtures for its dialect
+	options := setupOptions{SchemaPath: *schemaPath, SeedPath: *seedPath, Overwrite: *overwrite, NoFixtures: *noFixtures}
+	dbs := make([]*sql.DB, len(engines))
+	var fixtures Fixtures
+	for i := range engines {
+		db, engineFixtures, cleanup, err := openEngine(&engines[i], options)
+		if err != nil {
+			return fmt.Errorf("Error setting up %s database: %w", engines[i].Name, err)
+		}
+		defer cleanup()
+		dbs[i] = db
+		if i == 0 {
+			fixtures = engineFixtures
+		}
 
-		// For the top-spending customer query, we need to handle multiple columns
-		if q.Name == "Top-spending Customer" {
-			err = db.QueryRow(q.SQL).Scan(&customer, &result)
+		if exporting {
+			continue
+		}
+		location := engines[i].DSN
+		if engines[i].Dialect() != "sqlite" {
+			location = engines[i].Name
+		}
+		if *noFixtures {
+			fmt.Printf("✅ Using existing database %s without fixtures\n", location)
 		} else {
-			err = db.QueryRow(q.SQL).Scan(&result)
+			fmt.Printf("✅ Database %s created and populated from fixtures\n", location)
 		}
+	}
 
-		if err != nil {
-			fmt.Printf("❌ Error executing query: %v\n", err)
-			continue
+	if exporting {
+		if err := exportToFile(ctx, dbs[0], *exportSQL, *exportFormat, *exportPath, os.Stdout); err != nil {
+			return fmt.Errorf("Error exporting query result: %w", err)
 		}
+		return nil
+	}
+	fmt.Println()
 
-		if q.Name == "Top-spending Customer" {
-			fmt.Printf("Result: %s (%.2f)\n", customer, result)
-			if result == q.Expected && customer == "Alice" {
-				fmt.Println("✅ Result matches expected value")
-			} else {
-				fmt.Printf("❌ Result does not match expected value. Expected: Alice (%.2f)\n", q.Expected)
-			}
-		} else {
-			fmt.Printf("Result: %.2f\n", result)
-			if result == q.Expected {
-				fmt.Println("✅ Result matches expected value")
-			} else {
-				fmt.Printf("❌ Result does not match expected value. Expected: %.2f\n", q.Expected)
-			}
+	// Check if web mode is enabled
+	if *webMode {
+		fmt.Printf("Starting web interface on port %d...\n", *webPort)
+		limits := QueryLimits{
+			ReadOnly: *webReadOnly,
+			Allowed:  parseAllowed(*webAllow),
+			Timeout:  *webTimeout,
+			MaxRows:  *webMaxRows,
+			// Imports write to the database, so they are off unless asked for
+			AllowImport: *webImport,
 		}
-		fmt.Println()
+		return runWebServer(ctx, dbs[0], engines[0].Dialect(), *webPort, queries, fixtures, limits)
+	}
+
+	// Run the queries on every engine and check results
+	results := make([][]TestResult, len(engines))
+	for i, engine := range engines {
+		if len(engines) > 1 {
+			fmt.Printf("Engine: %s\n", engine.Name)
+			fmt.Println("================================")
+			fmt.Println()
+		}
+		results[i] = runSuite(ctx, dbs[i], queries)
+		if ctx.Err() != nil {
+			return errors.New("interrupted")
+		}
+	}
+
+	if len(engines) > 1 {
+		printDivergences(queries, engines, compareEngines(queries, engines, results))
 	}
 
 	// Output a prompt to view the data
@@ -125,44 +194,45 @@ func main() {
 	fmt.Println()
 
 	fmt.Println("All tests completed.")
+	return nil
 }
 
-// setupDatabase creates and populates the orders table
-func setupDatabase(db *sql.DB) error {
-	// Drop the table if it exists
-	_, err := db.Exec("DROP TABLE IF EXISTS orders")
-	if err != nil {
-		return fmt.Errorf("failed to drop table: %w", err)
-	}
+// runSuite runs every test against db, printing each result
+func runSuite(ctx context.Context, db *sql.DB, queries []Query) []TestResult {
+	var results []TestResult
+	for _, q := range queries {
+		if ctx.Err() != nil {
+			break
+		}
+		fmt.Printf("Test: %s\n", q.Name)
+		fmt.Printf("Description: %s\n", q.Description)
+		fmt.Printf("SQL Query: %s\n", q.SQL)
 
-	// Create the orders table
-	_, err = db.Exec(`
-		CREATE TABLE orders (
-			id INTEGER PRIMARY KEY,
-			customer TEXT,
-			amount REAL,
-			order_date DATE
-		)
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to create table: %w", err)
-	}
+		result := runTest(ctx, db, q)
+		results = append(results, result)
+		if result.Error != "" {
+			fmt.Printf("❌ Error executing query: %s\n", result.Error)
+			fmt.Println()
+			continue
+		}
 
-	// Insert sample data
-	_, err = db.Exec(`
-		INSERT INTO orders (customer, amount, order_date) VALUES
-		('Alice', 5000, '2024-03-01'),
-		('Bob', 8000, '2024-03-05'),
-		('Alice', 3000, '2024-03-15'),
-		('Charlie', 7000, '2024-02-20'),
-		('Alice', 10000, '2024-02-28'),
-		('Bob', 4000, '2024-02-10'),
-		('Charlie', 9000, '2024-03-22'),
-		('Alice', 2000, '2024-03-30')
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to insert data: %w", err)
+		if len(result.Actual.Columns) > 0 {
+			fmt.Println("Result:")
+			printResultSet(result.Actual)
+		}
+		if q.ExpectAffected != nil || len(result.Actual.Columns) == 0 {
+			fmt.Printf("Rows affected: %d\n", result.Affected)
+		}
+		switch {
+		case q.Expected == nil:
+			fmt.Println("✅ Query ran successfully (no expected result)")
+		case result.Passed:
+			fmt.Println("✅ Result matches expected value")
+		default:
+			fmt.Printf("❌ Result does not match expected value (%d difference(s)):\n", len(result.Diff))
+			printDiff(result.Diff)
+		}
+		fmt.Println()
 	}
-
-	return nil
+	return results
 }
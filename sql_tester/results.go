@@ -0,0 +1,550 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expectation is the result set a test expects
+type Expectation struct {
+	// Columns, when set, must match the result's column names in order (case-insensitive)
+	Columns []string        `json:"columns,omitempty"`
+	Rows    [][]interface{} `json:"rows"`
+	// Ordered compares rows position by position; otherwise row order is ignored
+	Ordered bool `json:"ordered"`
+	// Tolerance is the largest difference at which two numbers still count as equal
+	Tolerance float64 `json:"tolerance"`
+}
+
+// ResultSet is the outcome of a query: column names and rows of values
+type ResultSet struct {
+	Columns []string        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+// RowDiff describes one difference between the expected and actual result sets
+type RowDiff struct {
+	// Kind is "affected", "columns", "missing", "unexpected" or "changed"
+	Kind     string        `json:"kind"`
+	Row      int           `json:"row,omitempty"`
+	Expected []interface{} `json:"expected,omitempty"`
+	Actual   []interface{} `json:"actual,omitempty"`
+}
+
+// TestResult is the outcome of running one suite test
+type TestResult struct {
+	Name   string    `json:"name"`
+	Passed bool      `json:"passed"`
+	Error  string    `json:"error,omitempty"`
+	Actual ResultSet `json:"actual"`
+	// Affected is the number of rows changed by the test's DML statements
+	Affected int64     `json:"affected"`
+	Diff     []RowDiff `json:"diff,omitempty"`
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// runQuery executes sql and reads every row into a ResultSet
+func runQuery(ctx context.Context, db queryer, query string) (ResultSet, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return ResultSet{}, err
+	}
+	defer rows.Close()
+
+	result, _, err := scanRows(rows, 0)
+	return result, err
+}
+
+// scanRows reads up to maxRows rows (all of them if maxRows is 0) into a ResultSet.
+// truncated reports whether rows were left unread
+func scanRows(rows *sql.Rows, maxRows int) (result ResultSet, truncated bool, err error) {
+	truncated, err = readRows(rows, 0, maxRows, func(columns []string) error {
+		result = ResultSet{Columns: columns, Rows: [][]interface{}{}}
+		return nil
+	}, func(row []interface{}) error {
+		result.Rows = append(result.Rows, row)
+		return nil
+	})
+	if err != nil {
+		return ResultSet{}, false, err
+	}
+	return result, truncated, nil
+}
+
+// readRows passes the column names and then every row to the callbacks as they are read,
+// so results never have to be held in memory. The first offset rows are skipped, and at
+// most maxRows rows (all of them if maxRows is 0) are passed on; truncated reports whether
+// rows were left unread
+func readRows(rows *sql.Rows, offset, maxRows int, onColumns func([]string) error, onRow func([]interface{}) error) (truncated bool, err error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return false, fmt.Errorf("error getting columns: %w", err)
+	}
+	if err := onColumns(columns); err != nil {
+		return false, err
+	}
+
+	// PostgreSQL and MySQL return NUMERIC and DECIMAL values as text; read them as numbers
+	// so results compare equal across engines
+	decimal := make([]bool, len(columns))
+	if types, err := rows.ColumnTypes(); err == nil {
+		for i, columnType := range types {
+			switch strings.ToUpper(columnType.DatabaseTypeName()) {
+			case "NUMERIC", "DECIMAL":
+				decimal[i] = true
+			}
+		}
+	}
+
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range columns {
+		valuePtrs[i] = &values[i]
+	}
+
+	skipped, count := 0, 0
+	for rows.Next() {
+		if skipped < offset {
+			skipped++
+			continue
+		}
+		if maxRows > 0 && count == maxRows {
+			truncated = true
+			break
+		}
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return false, fmt.Errorf("error scanning row: %w", err)
+		}
+		row := make([]interface{}, len(columns))
+		for i, value := range values {
+			row[i] = normalizeValue(value)
+			if text, ok := row[i].(string); ok && decimal[i] {
+				if number, err := strconv.ParseFloat(text, 64); err == nil {
+					row[i] = number
+				}
+			}
+		}
+		if err := onRow(row); err != nil {
+			return false, err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return truncated, nil
+}
+
+// normalizeValue converts driver values into JSON-friendly ones
+func normalizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case time.Time:
+		// DATE columns come back as times; show plain dates without a clock
+		if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 && v.Nanosecond() == 0 {
+			return v.Format("2006-01-02")
+		}
+		return v.Format(time.RFC3339)
+	}
+	return value
+}
+
+// runTest executes a suite test inside a transaction that is always rolled back, so
+// setup SQL and DML never leak into later tests. Tests without an expectation pass when
+// their SQL runs. Cancelling ctx aborts the test.
+//
+// Teardown SQL runs after the rollback, outside the transaction, even when the test
+// failed. It is meant for changes the rollback can't undo, such as tables created on
+// MySQL, whose DDL statements commit implicitly
+func runTest(ctx context.Context, db *sql.DB, q Query) TestResult {
+	result := runTestTransaction(ctx, db, q)
+	for _, statement := range q.Teardown {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			if result.Error == "" {
+				result.Error = fmt.Sprintf("teardown failed: %v", err)
+			}
+			result.Passed = false
+			break
+		}
+	}
+	return result
+}
+
+// runTestTransaction runs a test's setup and SQL in a transaction and rolls it back
+func runTestTransaction(ctx context.Context, db *sql.DB, q Query) TestResult {
+	result := TestResult{Name: q.Name, Actual: ResultSet{Columns: []string{}, Rows: [][]interface{}{}}}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to begin transaction: %v", err)
+		return result
+	}
+	defer tx.Rollback()
+
+	for _, statement := range q.Setup {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			result.Error = fmt.Sprintf("setup failed: %v", err)
+			return result
+		}
+	}
+
+	// Every statement but a final query is executed, counting the rows it changes
+	statements := splitStatements(q.SQL)
+	var affected int64
+	for i, statement := range statements {
+		if i == len(statements)-1 && returnsRows(statement) {
+			result.Actual, err = runQuery(ctx, tx, statement)
+			if err != nil {
+				result.Error = err.Error()
+				return result
+			}
+			continue
+		}
+		res, err := tx.ExecContext(ctx, statement)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			affected += n
+		}
+	}
+	result.Affected = affected
+
+	if q.ExpectAffected != nil && *q.ExpectAffected != affected {
+		result.Diff = append(result.Diff, RowDiff{Kind: "affected", Expected: []interface{}{*q.ExpectAffected}, Actual: []interface{}{affected}})
+	}
+	if q.Expected != nil {
+		result.Diff = append(result.Diff, compareResults(*q.Expected, result.Actual)...)
+	}
+	result.Passed = len(result.Diff) == 0
+	return result
+}
+
+// returnsRows reports whether a statement is a query rather than DML or DDL
+func returnsRows(statement string) bool {
+	tokens := sqlTokens(statement)
+	switch statementKeyword(tokens) {
+	case "SELECT", "WITH", "VALUES", "PRAGMA", "EXPLAIN":
+		return true
+	case "":
+		return false
+	}
+	// INSERT/UPDATE/DELETE ... RETURNING produce rows too, but not a RETURNING inside a subquery
+	depth := 0
+	for _, token := range tokens {
+		switch token {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		case "RETURNING":
+			if depth == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// statementKeyword returns the keyword of the statement a WITH clause introduces, as in
+// "WITH t AS (...) DELETE ...", or else the first keyword. WITH is returned when the
+// statement after the common table expressions can't be found
+func statementKeyword(tokens []string) string {
+	first := 0
+	for first < len(tokens) && tokens[first] == "(" {
+		first++
+	}
+	if first == len(tokens) {
+		return ""
+	}
+	if tokens[first] != "WITH" {
+		return tokens[first]
+	}
+
+	// Each expression ends with its parenthesized body, followed by a comma before the
+	// next one; column lists are followed by AS instead
+	depth := 0
+	for i := first + 1; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth != 0 {
+				continue
+			}
+			for next := i + 1; next < len(tokens); next++ {
+				if tokens[next] != "(" {
+					if tokens[next] != "AS" && tokens[next] != "," {
+						return tokens[next]
+					}
+					break
+				}
+			}
+		}
+	}
+	return "WITH"
+}
+
+// sqlTokens splits a statement into upper-case words and the punctuation "(", ")" and ",".
+// Comments are skipped, and quoted strings and identifiers become a single `"` token, so
+// that words inside them are never mistaken for keywords
+func sqlTokens(statement string) []string {
+	var tokens []string
+
+	for i := 0; i < len(statement); i++ {
+		c := statement[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			end := strings.IndexByte(statement[i+1:], closing)
+			if end < 0 {
+				return tokens
+			}
+			tokens = append(tokens, `"`)
+			i += end + 1
+		case c == '-' && i+1 < len(statement) && statement[i+1] == '-':
+			end := strings.IndexByte(statement[i:], '\n')
+			if end < 0 {
+				return tokens
+			}
+			i += end
+		case c == '/' && i+1 < len(statement) && statement[i+1] == '*':
+			end := strings.Index(statement[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 3
+		case c == '(':
+			tokens = append(tokens, "(")
+		case c == ')':
+			tokens = append(tokens, ")")
+		case c == ',':
+			tokens = append(tokens, ",")
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80:
+			end := i + 1
+			for end < len(statement) {
+				d := statement[end]
+				if d != '_' && d != '$' && d != '.' && !(d >= 'a' && d <= 'z' || d >= 'A' && d <= 'Z' || d >= '0' && d <= '9' || d >= 0x80) {
+					break
+				}
+				end++
+			}
+			tokens = append(tokens, strings.ToUpper(statement[i:end]))
+			i = end - 1
+		}
+	}
+	return tokens
+}
+
+// compareResults lists the differences between the expected and actual result sets
+func compareResults(expected Expectation, actual ResultSet) []RowDiff {
+	var diffs []RowDiff
+
+	if len(expected.Columns) > 0 && !sameColumns(expected.Columns, actual.Columns) {
+		diffs = append(diffs, RowDiff{Kind: "columns", Expected: stringsToValues(expected.Columns), Actual: stringsToValues(actual.Columns)})
+	}
+
+	if expected.Ordered {
+		for i := 0; i < len(expected.Rows) || i < len(actual.Rows); i++ {
+			switch {
+			case i >= len(actual.Rows):
+				diffs = append(diffs, RowDiff{Kind: "missing", Row: i + 1, Expected: expected.Rows[i]})
+			case i >= len(expected.Rows):
+				diffs = append(diffs, RowDiff{Kind: "unexpected", Row: i + 1, Actual: actual.Rows[i]})
+			case !rowsEqual(expected.Rows[i], actual.Rows[i], expected.Tolerance):
+				diffs = append(diffs, RowDiff{Kind: "changed", Row: i + 1, Expected: expected.Rows[i], Actual: actual.Rows[i]})
+			}
+		}
+		return diffs
+	}
+
+	// Without ordering, pair every expected row with the first equal actual row left over
+	matched := make([]bool, len(actual.Rows))
+	for _, want := range expected.Rows {
+		found := false
+		for i, got := range actual.Rows {
+			if !matched[i] && rowsEqual(want, got, expected.Tolerance) {
+				matched[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			diffs = append(diffs, RowDiff{Kind: "missing", Expected: want})
+		}
+	}
+	for i, got := range actual.Rows {
+		if !matched[i] {
+			diffs = append(diffs, RowDiff{Kind: "unexpected", Row: i + 1, Actual: got})
+		}
+	}
+	return diffs
+}
+
+// sameColumns compares column names in order, ignoring case
+func sameColumns(expected, actual []string) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	for i := range expected {
+		if !strings.EqualFold(strings.TrimSpace(expected[i]), actual[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// stringsToValues converts column names for use in a RowDiff
+func stringsToValues(values []string) []interface{} {
+	converted := make([]interface{}, len(values))
+	for i, v := range values {
+		converted[i] = v
+	}
+	return converted
+}
+
+// rowsEqual compares two rows value by value
+func rowsEqual(expected, actual []interface{}, tolerance float64) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	for i := range expected {
+		if !valuesEqual(expected[i], actual[i], tolerance) {
+			return false
+		}
+	}
+	return true
+}
+
+// valuesEqual compares numbers within tolerance and everything else by its text
+func valuesEqual(expected, actual interface{}, tolerance float64) bool {
+	if expected == nil || actual == nil {
+		return expected == nil && actual == nil
+	}
+	expectedNumber, expectedIsNumber := toNumber(expected)
+	actualNumber, actualIsNumber := toNumber(actual)
+	if expectedIsNumber && actualIsNumber {
+		return math.Abs(expectedNumber-actualNumber) <= tolerance
+	}
+	return fmt.Sprint(expected) == fmt.Sprint(actual)
+}
+
+// toNumber converts numeric values to float64
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case bool:
+		// SQLite has no booleans, so they arrive as 0 and 1
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// parseExpectedRow reads a comma-separated row: numbers become numbers, NULL becomes nil
+// and quoted or bare text stays text
+func parseExpectedRow(text string) ([]interface{}, error) {
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.TrimLeadingSpace = true
+	reader.LazyQuotes = true
+	fields, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	row := make([]interface{}, len(fields))
+	for i, field := range fields {
+		field = strings.TrimSpace(field)
+		switch {
+		case strings.EqualFold(field, "NULL"):
+			row[i] = nil
+		case len(field) >= 2 && field[0] == '\'' && field[len(field)-1] == '\'':
+			row[i] = field[1 : len(field)-1]
+		default:
+			if number, err := strconv.ParseFloat(field, 64); err == nil {
+				row[i] = number
+			} else {
+				row[i] = field
+			}
+		}
+	}
+	return row, nil
+}
+
+// formatRow renders a row as "a | b | c"
+func formatRow(row []interface{}) string {
+	parts := make([]string, len(row))
+	for i, value := range row {
+		parts[i] = formatValue(value)
+	}
+	return strings.Join(parts, " | ")
+}
+
+// formatValue renders a single value, showing whole numbers without decimals
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// printResultSet displays a result set as a simple table
+func printResultSet(result ResultSet) {
+	fmt.Printf("  %s\n", strings.Join(result.Columns, " | "))
+	for _, row := range result.Rows {
+		fmt.Printf("  %s\n", formatRow(row))
+	}
+	if len(result.Rows) == 0 {
+		fmt.Println("  (no rows)")
+	}
+}
+
+// printDiff displays the row-level differences of a failed test
+func printDiff(diffs []RowDiff) {
+	for _, diff := range diffs {
+		switch diff.Kind {
+		case "affected":
+			fmt.Printf("  ~ rows affected: expected %s, got %s\n", formatRow(diff.Expected), formatRow(diff.Actual))
+		case "columns":
+			fmt.Printf("  ~ columns: expected %s, got %s\n", formatRow(diff.Expected), formatRow(diff.Actual))
+		case "missing":
+			if diff.Row > 0 {
+				fmt.Printf("  - row %d missing: %s\n", diff.Row, formatRow(diff.Expected))
+			} else {
+				fmt.Printf("  - missing: %s\n", formatRow(diff.Expected))
+			}
+		case "unexpected":
+			fmt.Printf("  + row %d unexpected: %s\n", diff.Row, formatRow(diff.Actual))
+		case "changed":
+			fmt.Printf("  ~ row %d: expected %s, got %s\n", diff.Row, formatRow(diff.Expected), formatRow(diff.Actual))
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+// openTestDB returns an in-memory database with the sample orders
+func openTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	// Every connection to :memory: is a separate database
+	db.SetMaxOpenConns(1)
+	if err := applyFixtures(db, Fixtures{Schema: defaultSchema, Seed: defaultSeed}, false); err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	return db
+}
+
+func TestBuiltinSuitePasses(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	queries, err := loadSuite("")
+	if err != nil {
+		t.Fatalf("Failed to load built-in suite: %v", err)
+	}
+	for _, q := range queries {
+		t.Run(q.Name, func(t *testing.T) {
+			result := runTest(context.Background(), db, q)
+			if !result.Passed {
+				t.Errorf("Expected test to pass, got error %q and diff %+v", result.Error, result.Diff)
+			}
+		})
+	}
+}
+
+func TestCompareResults(t *testing.T) {
+	actual := ResultSet{
+		Columns: []string{"customer", "total"},
+		Rows: [][]interface{}{
+			{"Alice", 20000.0},
+			{"Bob", int64(12000)},
+			{"Charlie", 16000.004},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		expected      Expectation
+		expectedKinds []string
+	}{
+		{
+			name: "Unordered match",
+			expected: Expectation{Rows: [][]interface{}{
+				{"Charlie", 16000.004}, {"Alice", 20000.0}, {"Bob", 12000.0},
+			}},
+		},
+		{
+			name: "Ordered mismatch",
+			expected: Expectation{Ordered: true, Rows: [][]interface{}{
+				{"Bob", 12000.0}, {"Alice", 20000.0}, {"Charlie", 16000.004},
+			}},
+			expectedKinds: []string{"changed", "changed"},
+		},
+		{
+			name: "Within tolerance",
+			expected: Expectation{Tolerance: 0.01, Rows: [][]interface{}{
+				{"Alice", 20000.0}, {"Bob", 12000.0}, {"Charlie", 16000.0},
+			}},
+		},
+		{
+			name: "Outside tolerance",
+			expected: Expectation{Rows: [][]interface{}{
+				{"Alice", 20000.0}, {"Bob", 12000.0}, {"Charlie", 16000.0},
+			}},
+			expectedKinds: []string{"missing", "unexpected"},
+		},
+		{
+			name:          "Missing and unexpected rows",
+			expected:      Expectation{Ordered: true, Rows: [][]interface{}{{"Alice", 20000.0}}},
+			expectedKinds: []string{"unexpected", "unexpected"},
+		},
+		{
+			name: "Column names differ",
+			expected: Expectation{Columns: []string{"customer", "total_spent"}, Rows: [][]interface{}{
+				{"Alice", 20000.0}, {"Bob", 12000.0}, {"Charlie", 16000.004},
+			}},
+			expectedKinds: []string{"columns"},
+		},
+		{
+			name:          "NULL only equals NULL",
+			expected:      Expectation{Rows: [][]interface{}{{"Alice", nil}, {"Bob", 12000.0}, {"Charlie", 16000.004}}},
+			expectedKinds: []string{"missing", "unexpected"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			diffs := compareResults(tc.expected, actual)
+			if len(diffs) != len(tc.expectedKinds) {
+				t.Fatalf("Expected %d differences, got %d: %+v", len(tc.expectedKinds), len(diffs), diffs)
+			}
+			for i, diff := range diffs {
+				if diff.Kind != tc.expectedKinds[i] {
+					t.Errorf("Expected difference %d to be %s, got %s", i, tc.expectedKinds[i], diff.Kind)
+				}
+			}
+		})
+	}
+}
+
+func TestParseExpectedRow(t *testing.T) {
+	row, err := parseExpectedRow(`Alice, 20000, NULL, '42', "Smith, John"`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []interface{}{"Alice", 20000.0, nil, "42", "Smith, John"}
+	if len(row) != len(expected) {
+		t.Fatalf("Expected %d values, got %d: %v", len(expected), len(row), row)
+	}
+	for i := range expected {
+		if row[i] != expected[i] {
+			t.Errorf("Expected value %d to be %#v, got %#v", i, expected[i], row[i])
+		}
+	}
+}
+
+func TestRunTestIsolation(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	affected := func(n int64) *int64 { return &n }
+	testCases := []struct {
+		name         string
+		query        Query
+		expectPassed bool
+		expectError  bool
+	}{
+		{
+			name: "Delete with affected and result expectations",
+			query: Query{
+				SQL:            "DELETE FROM orders WHERE customer = 'Bob';\nSELECT COUNT(*) FROM orders",
+				ExpectAffected: affected(2),
+				Expected:       &Expectation{Rows: [][]interface{}{{6.0}}},
+			},
+			expectPassed: true,
+		},
+		{
+			name: "Wrong affected count",
+			query: Query{
+				SQL:            "UPDATE orders SET amount = 0 WHERE customer = 'Alice'",
+				ExpectAffected: affected(3),
+			},
+			expectPassed: false,
+		},
+		{
+			name: "Setup data is visible to the test",
+			query: Query{
+				Setup:    []string{"INSERT INTO orders (customer, amount, order_date) VALUES ('Dana', 1, '2024-04-01')"},
+				SQL:      "SELECT customer FROM orders WHERE customer = 'Dana'",
+				Teardown: []string{"DELETE FROM orders WHERE customer = 'Dana'"},
+				Expected: &Expectation{Rows: [][]interface{}{{"Dana"}}},
+			},
+			expectPassed: true,
+		},
+		{
+			name:        "Failing setup",
+			query:       Query{Setup: []string{"INSERT INTO missing VALUES (1)"}, SQL: "SELECT 1"},
+			expectError: true,
+		},
+		{
+			name:        "Failing teardown",
+			query:       Query{SQL: "SELECT 1", Teardown: []string{"DELETE FROM missing"}},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := runTest(context.Background(), db, tc.query)
+			if tc.expectError {
+				if result.Error == "" {
+					t.Errorf("Expected an error")
+				}
+			} else if result.Passed != tc.expectPassed {
+				t.Errorf("Expected passed=%v, got %v (error %q, diff %+v)", tc.expectPassed, result.Passed, result.Error, result.Diff)
+			}
+
+			// Every test is rolled back, so the fixture data is unchanged
+			var count int
+			var total float64
+			db.QueryRow("SELECT COUNT(*), SUM(amount) FROM orders").Scan(&count, &total)
+			if count != 8 || total != 48000 {
+				t.Errorf("Expected 8 orders totalling 48000 after the test, got %d totalling %.0f", count, total)
+			}
+		})
+	}
+
+	// Teardown runs after the rollback, so its changes are kept, even when the test fails
+	result := runTest(context.Background(), db, Query{
+		SQL:      "SELECT * FROM missing",
+		Teardown: []string{"CREATE TABLE teardown_log (note TEXT)", "INSERT INTO teardown_log VALUES ('done')"},
+	})
+	if !strings.Contains(result.Error, "no such table: missing") {
+		t.Errorf("Expected the test's own error, got %q", result.Error)
+	}
+	var note string
+	if err := db.QueryRow("SELECT note FROM teardown_log").Scan(&note); err != nil || note != "done" {
+		t.Errorf("Expected the teardown to be kept, got %q (%v)", note, err)
+	}
+}
+
+func TestReturnsRows(t *testing.T) {
+	testCases := []struct {
+		statement string
+		expected  bool
+	}{
+		{"SELECT 1", true},
+		{"  with t as (select 1) select * from t", true},
+		{"-- comment\nSELECT 1", true},
+		{"/* block */ VALUES (1)", true},
+		{"UPDATE orders SET amount = 1", false},
+		{"DELETE FROM orders RETURNING id", true},
+		{"CREATE TABLE t (x)", false},
+		{"UPDATE orders SET customer = 'returning customer'", false},
+		{"UPDATE orders SET customer = 'x' -- returning\n", false},
+		{`UPDATE "returning" SET x = 1 /* RETURNING */`, false},
+		{"UPDATE orders SET returning_customer = 1", false},
+		{"DELETE FROM orders WHERE id IN (SELECT id FROM t RETURNING id)", false},
+		{"insert into orders (customer) values ('x') returning id", true},
+		{"WITH t AS (SELECT 1) DELETE FROM orders", false},
+		{"WITH t AS (SELECT 1) DELETE FROM orders RETURNING id", true},
+		{"(SELECT 1)", true},
+	}
+
+	for _, tc := range testCases {
+		if actual := returnsRows(tc.statement); actual != tc.expected {
+			t.Errorf("Expected returnsRows(%q) to be %v, got %v", tc.statement, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// QueryLimits sandbox the custom queries sent to /api/query
+type QueryLimits struct {
+	// ReadOnly runs queries in a read-only transaction that is always rolled back; on
+	// SQLite the connection is also switched to PRAGMA query_only
+	ReadOnly bool
+	// Allowed lists the statement keywords accepted, such as SELECT; empty allows any
+	Allowed []string
+	// Timeout cancels queries running longer; zero means no timeout
+	Timeout time.Duration
+	// MaxRows caps the rows returned; zero means no cap
+	MaxRows int
+	// AllowImport accepts file uploads to /api/import, which write outside the sandbox
+	AllowImport bool
+}
+
+// defaultQueryLimits only let the web interface read data
+var defaultQueryLimits = QueryLimits{
+	ReadOnly: true,
+	Allowed:  []string{"SELECT", "WITH", "EXPLAIN"},
+	Timeout:  5 * time.Second,
+	MaxRows:  1000,
+}
+
+// Page selects the rows of a result to return
+type Page struct {
+	// Offset is the number of rows to skip
+	Offset int
+	// Limit is the number of rows to return; zero returns up to the MaxRows cap
+	Limit int
+}
+
+// parseAllowed reads a comma-separated keyword list, as given to -web-allow
+func parseAllowed(list string) []string {
+	var keywords []string
+	for _, keyword := range strings.Split(list, ",") {
+		if keyword = strings.ToUpper(strings.TrimSpace(keyword)); keyword != "" {
+			keywords = append(keywords, keyword)
+		}
+	}
+	return keywords
+}
+
+// errQueryRejected marks queries refused by the sandbox before they reach the database
+var errQueryRejected = errors.New("query rejected")
+
+// checkStatement accepts a single statement whose keywords are all allowed, as listed by
+// statementKeywords, so "WITH t AS (...) DELETE ..." and "EXPLAIN ANALYZE DELETE ..." count
+// as a DELETE
+func (l QueryLimits) checkStatement(query string) (string, error) {
+	statements := splitStatements(query)
+	switch {
+	case len(statements) == 0:
+		return "", fmt.Errorf("%w: the query is empty", errQueryRejected)
+	case len(statements) > 1:
+		return "", fmt.Errorf("%w: only one statement can be run at a time, got %d", errQueryRejected, len(statements))
+	}
+	if len(l.Allowed) == 0 {
+		return statements[0], nil
+	}
+	for _, keyword := range statementKeywords(sqlTokens(statements[0])) {
+		if !l.allows(keyword) {
+			return "", fmt.Errorf("%w: %s statements are not allowed (allowed: %s)", errQueryRejected, keyword, strings.Join(l.Allowed, ", "))
+		}
+	}
+	return statements[0], nil
+}
+
+// cteWriteKeywords start the statements that modify data from inside a common table
+// expression, as in "WITH d AS (DELETE ... RETURNING *) SELECT ..."
+var cteWriteKeywords = map[string]bool{"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true}
+
+// explainOptions are the words between EXPLAIN and the statement it explains
+var explainOptions = map[string]bool{
+	"ANALYZE": true, "ANALYSE": true, "VERBOSE": true, "QUERY": true, "PLAN": true, "EXTENDED": true, "PARTITIONS": true,
+}
+
+// statementKeywords lists the keywords a statement runs: its first keyword, the statement
+// under an EXPLAIN, the statement after a WITH clause's common table expressions, and any
+// data-modifying statement inside them
+func statementKeywords(tokens []string) []string {
+	var keywords []string
+	add := func(keyword string) {
+		for _, k := range keywords {
+			if k == keyword {
+				return
+			}
+		}
+		keywords = append(keywords, keyword)
+	}
+
+	for {
+		for len(tokens) > 0 && tokens[0] == "(" {
+			tokens = tokens[1:]
+		}
+		if len(tokens) == 0 {
+			if len(keywords) == 0 {
+				add("")
+			}
+			return keywords
+		}
+		add(tokens[0])
+		switch tokens[0] {
+		case "EXPLAIN":
+			tokens = explainedStatement(tokens[1:])
+			continue
+		case "WITH":
+			add(statementKeyword(tokens))
+			for _, token := range tokens[1:] {
+				if cteWriteKeywords[token] {
+					add(token)
+				}
+			}
+		}
+		return keywords
+	}
+}
+
+// explainedStatement skips the options after EXPLAIN, such as ANALYZE, QUERY PLAN,
+// FORMAT=JSON or "(ANALYZE, BUFFERS)", and returns the tokens of the explained statement
+func explainedStatement(tokens []string) []string {
+	// A parenthesized option list starts with an option name rather than a statement
+	if len(tokens) > 1 && tokens[0] == "(" && !isStatementStart(tokens[1]) {
+		depth := 0
+		for i, token := range tokens {
+			switch token {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			}
+			if depth == 0 {
+				tokens = tokens[i+1:]
+				break
+			}
+		}
+	}
+	for len(tokens) > 0 {
+		switch {
+		case explainOptions[tokens[0]]:
+			tokens = tokens[1:]
+		case tokens[0] == "FORMAT" && len(tokens) > 1:
+			tokens = tokens[2:]
+		default:
+			return tokens
+		}
+	}
+	return tokens
+}
+
+// isStatementStart reports whether a keyword starts a statement that can be explained
+func isStatementStart(keyword string) bool {
+	switch keyword {
+	case "(", "SELECT", "WITH", "VALUES", "TABLE", "INSERT", "UPDATE", "DELETE", "MERGE", "REPLACE":
+		return true
+	}
+	return false
+}
+
+// allows reports whether a statement keyword is in the allow-list
+func (l QueryLimits) allows(keyword string) bool {
+	for _, allowed := range l.Allowed {
+		if keyword == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// runSandboxedQuery runs a custom query within the limits, passing the column names and
+// then the page's rows to the callbacks as they are read. The page never exceeds MaxRows
+// rows; truncated reports whether more rows follow it
+func runSandboxedQuery(ctx context.Context, db *sql.DB, dialect, query string, limits QueryLimits, page Page,
+	onColumns func([]string) error, onRow func([]interface{}) error) (truncated bool, err error) {
+	statement, err := limits.checkStatement(query)
+	if err != nil {
+		return false, err
+	}
+	limit := page.Limit
+	if limits.MaxRows > 0 && (limit <= 0 || limit > limits.MaxRows) {
+		limit = limits.MaxRows
+	}
+
+	if limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.Timeout)
+		defer cancel()
+	}
+
+	truncated, err = runOnConn(ctx, db, dialect, statement, limits.ReadOnly, func(rows *sql.Rows) (bool, error) {
+		return readRows(rows, page.Offset, limit, onColumns, onRow)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			return false, fmt.Errorf("query timed out after %s", limits.Timeout)
+		case errors.Is(ctx.Err(), context.Canceled):
+			return false, errors.New("query was cancelled")
+		}
+	}
+	return truncated, err
+}
+
+// runOnConn executes the statement and hands its rows to read. Read-only statements run on
+// a dedicated connection, so that the SQLite query_only pragma never affects other users
+// of the pool
+func runOnConn(ctx context.Context, db *sql.DB, dialect, statement string, readOnly bool, read func(*sql.Rows) (bool, error)) (bool, error) {
+	if !readOnly {
+		rows, err := db.QueryContext(ctx, statement)
+		if err != nil {
+			return false, err
+		}
+		defer rows.Close()
+		return read(rows)
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	// SQLite ignores read-only transactions, so the connection itself is made read-only
+	if dialect == "sqlite" {
+		if _, err := conn.ExecContext(ctx, "PRAGMA query_only = ON"); err != nil {
+			return false, fmt.Errorf("failed to make connection read-only: %w", err)
+		}
+		defer func() {
+			// The statement's context may be done, so the pragma is reset without it. A
+			// connection that can't be reset is discarded instead of returned to the pool
+			if _, err := conn.ExecContext(context.Background(), "PRAGMA query_only = OFF"); err != nil {
+				conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+			}
+		}()
+	}
+
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return false, fmt.Errorf("failed to begin read-only transaction: %w", err)
+	}
+	// Nothing a custom query does is ever committed
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, statement)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	return read(rows)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// collectSandboxedQuery runs a sandboxed query on SQLite and collects its result
+func collectSandboxedQuery(db *sql.DB, query string, limits QueryLimits, page Page) (ResultSet, bool, error) {
+	var result ResultSet
+	truncated, err := runSandboxedQuery(context.Background(), db, "sqlite", query, limits, page, func(columns []string) error {
+		result.Columns = columns
+		return nil
+	}, func(row []interface{}) error {
+		result.Rows = append(result.Rows, row)
+		return nil
+	})
+	return result, truncated, err
+}
+
+func TestRunSandboxedQuery(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	testCases := []struct {
+		name          string
+		query         string
+		limits        QueryLimits
+		expectedRows  int
+		truncated     bool
+		expectError   string
+		expectRejects bool
+	}{
+		{"Select", "SELECT * FROM orders", defaultQueryLimits, 8, false, "", false},
+		{"With and comment", "-- customers\nWITH c AS (SELECT DISTINCT customer FROM orders) SELECT * FROM c;", defaultQueryLimits, 3, false, "", false},
+		{"Row cap", "SELECT * FROM orders", QueryLimits{ReadOnly: true, MaxRows: 5}, 5, true, "", false},
+		{"Drop rejected", "DROP TABLE orders", defaultQueryLimits, 0, false, "DROP statements are not allowed", true},
+		{"Delete behind a WITH clause rejected", "WITH x AS (SELECT 1) DELETE FROM orders", defaultQueryLimits, 0, false, "DELETE statements are not allowed", true},
+		{"Delete inside a common table expression rejected", "WITH d AS (DELETE FROM orders RETURNING *) SELECT * FROM d", defaultQueryLimits, 0, false, "DELETE statements are not allowed", true},
+		{"Insert in a nested common table expression rejected", "WITH a AS (SELECT 1), b AS (SELECT * FROM (INSERT INTO orders (customer) VALUES ('x') RETURNING id)) SELECT * FROM b", defaultQueryLimits, 0, false, "INSERT statements are not allowed", true},
+		{"Explain analyze delete rejected", "EXPLAIN ANALYZE DELETE FROM orders", defaultQueryLimits, 0, false, "DELETE statements are not allowed", true},
+		{"Explain with options rejected", "explain (analyze, format json) update orders set amount = 0", defaultQueryLimits, 0, false, "UPDATE statements are not allowed", true},
+		{"Explain query plan of a WITH delete rejected", "EXPLAIN QUERY PLAN WITH x AS (SELECT 1) DELETE FROM orders", defaultQueryLimits, 0, false, "DELETE statements are not allowed", true},
+		{"Explain select", "EXPLAIN QUERY PLAN SELECT * FROM orders", defaultQueryLimits, 1, false, "", false},
+		{"Several statements rejected", "SELECT 1; DELETE FROM orders", defaultQueryLimits, 0, false, "only one statement", true},
+		{"Empty rejected", "  -- nothing\n", defaultQueryLimits, 0, false, "empty", true},
+		{"Write blocked by read-only connection", "DELETE FROM orders", QueryLimits{ReadOnly: true}, 0, false, "readonly", false},
+		{"Timeout", "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT COUNT(*) FROM n", QueryLimits{ReadOnly: true, Timeout: 50 * time.Millisecond}, 0, false, "timed out after 50ms", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, truncated, err := collectSandboxedQuery(db, tc.query, tc.limits, Page{})
+			if tc.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectError) {
+					t.Fatalf("Expected error containing %q, got %v", tc.expectError, err)
+				}
+				if errors.Is(err, errQueryRejected) != tc.expectRejects {
+					t.Errorf("Expected rejection %v, got error %v", tc.expectRejects, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(result.Rows) != tc.expectedRows || truncated != tc.truncated {
+				t.Errorf("Expected %d rows (truncated %v), got %d (truncated %v)", tc.expectedRows, tc.truncated, len(result.Rows), truncated)
+			}
+		})
+	}
+
+	// Nothing was changed, and the connection is writable again for the test runner
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM orders").Scan(&count)
+	if count != 8 {
+		t.Errorf("Expected 8 orders to remain, got %d", count)
+	}
+	if _, err := db.Exec("UPDATE orders SET amount = amount WHERE id = 1"); err != nil {
+		t.Errorf("Expected the connection to be writable after a sandboxed query, got %v", err)
+	}
+
+	// Without read-only mode and allow-list, writes go through
+	if _, _, err := collectSandboxedQuery(db, "DELETE FROM orders WHERE customer = 'Bob'", QueryLimits{}, Page{}); err != nil {
+		t.Fatalf("Expected an unrestricted delete to succeed, got %v", err)
+	}
+	db.QueryRow("SELECT COUNT(*) FROM orders").Scan(&count)
+	if count != 6 {
+		t.Errorf("Expected 6 orders after the delete, got %d", count)
+	}
+}
+
+func TestExecuteQueryRejects(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		executeQuery(w, r, db, "sqlite", defaultQueryLimits, newRunningQueries())
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/query", strings.NewReader(`{"query": "DROP TABLE orders"}`))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d", http.StatusForbidden, rec.Code)
+	}
+	var body map[string]interface{}
+	json.NewDecoder(rec.Body).Decode(&body)
+	if !strings.Contains(body["error"].(string), "not allowed") {
+		t.Errorf("Expected a not allowed error, got %v", body)
+	}
+
+	// The allow-list looks past the common table expressions, even without read-only mode
+	writable := defaultQueryLimits
+	writable.ReadOnly = false
+	for _, query := range []string{
+		"WITH x AS (SELECT 1) DELETE FROM orders",
+		"with recursive x(n) as (select 1), y as materialized (select 2) update orders set amount = 0",
+		"WITH x AS (SELECT ')' AS p) INSERT INTO orders (customer) SELECT p FROM x",
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/query", strings.NewReader(`{"query": "`+query+`"}`))
+		rec := httptest.NewRecorder()
+		executeQuery(rec, req, db, "sqlite", writable, newRunningQueries())
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d for %q, got %d: %s", http.StatusForbidden, query, rec.Code, rec.Body.String())
+		}
+	}
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM orders WHERE amount > 0").Scan(&count)
+	if count != 8 {
+		t.Errorf("Expected 8 untouched orders, got %d", count)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/query", strings.NewReader(`{"query": "WITH c AS (SELECT customer FROM orders) SELECT * FROM c"}`))
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+	body = nil
+	json.NewDecoder(rec.Body).Decode(&body)
+	if rows, ok := body["rows"].([]interface{}); !ok || len(rows) != 8 || body["truncated"] != false {
+		t.Errorf("Expected 8 results, got %v", body)
+	}
+}
@@ -8,7 +8,7 @@ fi
 
 # Install required dependencies
 echo "Installing dependencies..."
-go get github.com/mattn/go-sqlite3
+go get github.com/mattn/go-sqlite3 github.com/lib/pq github.com/go-sql-driver/mysql
 
 # Run the application with web interface
 echo "Starting SQL Tester with web interface..."
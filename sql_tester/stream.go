@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// flushEvery is the number of rows written between flushes of a streamed response
+const flushEvery = 100
+
+// errBadCursor marks cursors that can't be decoded or belong to another query
+var errBadCursor = errors.New("invalid cursor")
+
+// pageCursor is the decoded form of a pagination cursor
+type pageCursor struct {
+	Offset int `json:"o"`
+	// Query is a hash of the query text, so a cursor only continues the query it came from
+	Query string `json:"q"`
+}
+
+// queryHash identifies a query's text in cursors
+func queryHash(query string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(query)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// encodeCursor returns the opaque cursor for the page of query starting at offset
+func encodeCursor(query string, offset int) string {
+	data, _ := json.Marshal(pageCursor{Offset: offset, Query: queryHash(query)})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns the offset stored in a cursor made for query
+func decodeCursor(cursor, query string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errBadCursor, err)
+	}
+	var decoded pageCursor
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Offset < 0 {
+		return 0, fmt.Errorf("%w: malformed cursor", errBadCursor)
+	}
+	if decoded.Query != queryHash(query) {
+		return 0, fmt.Errorf("%w: the cursor belongs to a different query", errBadCursor)
+	}
+	return decoded.Offset, nil
+}
+
+// pageSummary describes the returned page once all of its rows are written
+type pageSummary struct {
+	Offset    int    `json:"offset"`
+	Count     int    `json:"count"`
+	Truncated bool   `json:"truncated"`
+	Cursor    string `json:"next_cursor,omitempty"`
+}
+
+// resultStream writes a query's result to the response while its rows are read. Nothing
+// is written before the first row or the end of the result, so errors until then, such
+// as those raised when the database starts executing the query, get their own response
+type resultStream struct {
+	w       http.ResponseWriter
+	out     *bufio.Writer
+	ndjson  bool
+	id      string
+	columns []string
+	started bool
+	count   int
+}
+
+// newResultStream creates a stream in the requested format: "ndjson" for one JSON value
+// per line, otherwise a single JSON object whose rows are written as they arrive
+func newResultStream(w http.ResponseWriter, format, id string) *resultStream {
+	return &resultStream{w: w, out: bufio.NewWriter(w), ndjson: format == "ndjson", id: id}
+}
+
+// begin records the column names, which are written with the first row
+func (s *resultStream) begin(columns []string) error {
+	s.columns = columns
+	return nil
+}
+
+// start writes the response headers and the ordered column names
+func (s *resultStream) start() error {
+	s.started = true
+	header := struct {
+		ID      string   `json:"id"`
+		Columns []string `json:"columns"`
+	}{s.id, s.columns}
+	data, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+
+	if s.ndjson {
+		s.w.Header().Set("Content-Type", "application/x-ndjson")
+		s.out.Write(data)
+		s.out.WriteByte('\n')
+		return nil
+	}
+	// Leave the object open so the rows array can follow
+	s.w.Header().Set("Content-Type", "application/json")
+	s.out.Write(data[:len(data)-1])
+	s.out.WriteString(`,"rows":[`)
+	return nil
+}
+
+// row writes one row as an array of values
+func (s *resultStream) row(values []interface{}) error {
+	if !s.started {
+		if err := s.start(); err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	if !s.ndjson && s.count > 0 {
+		s.out.WriteByte(',')
+	}
+	s.out.Write(data)
+	if s.ndjson {
+		s.out.WriteByte('\n')
+	}
+	s.count++
+	if s.count%flushEvery == 0 {
+		return s.flush()
+	}
+	return nil
+}
+
+// end closes the result with the page summary, or the error that stopped it midway
+func (s *resultStream) end(summary pageSummary, queryErr error) error {
+	if !s.started {
+		if err := s.start(); err != nil {
+			return err
+		}
+	}
+	summary.Count = s.count
+	trailer := struct {
+		pageSummary
+		Error string `json:"error,omitempty"`
+	}{pageSummary: summary}
+	if queryErr != nil {
+		trailer.Error = queryErr.Error()
+	}
+	data, err := json.Marshal(trailer)
+	if err != nil {
+		return err
+	}
+
+	if s.ndjson {
+		s.out.Write(data)
+		s.out.WriteByte('\n')
+	} else {
+		// Merge the trailer's fields into the open object
+		s.out.WriteString("],")
+		s.out.Write(data[1:])
+		s.out.WriteByte('\n')
+	}
+	return s.flush()
+}
+
+// flush sends the buffered output to the client
+func (s *resultStream) flush() error {
+	if err := s.out.Flush(); err != nil {
+		return err
+	}
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCursor(t *testing.T) {
+	cursor := encodeCursor("SELECT * FROM orders", 40)
+	if offset, err := decodeCursor(cursor, "  SELECT * FROM orders\n"); err != nil || offset != 40 {
+		t.Errorf("Expected offset 40, got %d (%v)", offset, err)
+	}
+	if _, err := decodeCursor(cursor, "SELECT customer FROM orders"); !errors.Is(err, errBadCursor) {
+		t.Errorf("Expected a cursor for another query to be rejected, got %v", err)
+	}
+	if _, err := decodeCursor("not a cursor!", "SELECT 1"); !errors.Is(err, errBadCursor) {
+		t.Errorf("Expected a malformed cursor to be rejected, got %v", err)
+	}
+}
+
+func TestExecuteQueryPages(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	running := newRunningQueries()
+
+	post := func(body string, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/query", strings.NewReader(body))
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		executeQuery(rec, req, db, "sqlite", defaultQueryLimits, running)
+		return rec
+	}
+	query := `"SELECT id, customer, amount FROM orders ORDER BY id"`
+
+	// Walk the eight orders three at a time, following the cursors
+	var ids []float64
+	request := `{"query": ` + query + `, "limit": 3}`
+	for page := 0; page < 4; page++ {
+		rec := post(request, "")
+		var body struct {
+			Columns   []string        `json:"columns"`
+			Rows      [][]interface{} `json:"rows"`
+			Offset    int             `json:"offset"`
+			Count     int             `json:"count"`
+			Truncated bool            `json:"truncated"`
+			Cursor    string          `json:"next_cursor"`
+			Error     string          `json:"error"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode page %d: %v", page, err)
+		}
+		if body.Error != "" {
+			t.Fatalf("Expected no error, got %s", body.Error)
+		}
+		if !reflect.DeepEqual(body.Columns, []string{"id", "customer", "amount"}) {
+			t.Errorf("Expected columns in query order, got %v", body.Columns)
+		}
+		if body.Offset != len(ids) || body.Count != len(body.Rows) {
+			t.Errorf("Expected offset %d and count %d, got %d and %d", len(ids), len(body.Rows), body.Offset, body.Count)
+		}
+		for _, row := range body.Rows {
+			ids = append(ids, row[0].(float64))
+		}
+		if body.Cursor == "" {
+			if body.Truncated {
+				t.Errorf("Expected a cursor for a truncated page")
+			}
+			break
+		}
+		request = `{"query": ` + query + `, "limit": 3, "cursor": "` + body.Cursor + `"}`
+	}
+	if !reflect.DeepEqual(ids, []float64{1, 2, 3, 4, 5, 6, 7, 8}) {
+		t.Errorf("Expected ids 1 to 8 across the pages, got %v", ids)
+	}
+
+	// A cursor can't continue another query
+	cursor := encodeCursor("SELECT 1", 3)
+	if rec := post(`{"query": `+query+`, "cursor": "`+cursor+`"}`, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for a foreign cursor, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	// NDJSON has the columns, one line per row and a summary
+	rec := post(`{"query": `+query+`, "offset": 6}`, "application/x-ndjson")
+	if contentType := rec.Header().Get("Content-Type"); contentType != "application/x-ndjson" {
+		t.Errorf("Expected NDJSON content type, got %s", contentType)
+	}
+	var lines []string
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 4 {
+		t.Fatalf("Expected 4 lines, got %q", lines)
+	}
+	if !strings.Contains(lines[0], `"columns":["id","customer","amount"]`) || lines[1] != `[7,"Charlie",9000]` ||
+		!strings.Contains(lines[3], `"offset":6,"count":2,"truncated":false`) {
+		t.Errorf("Unexpected NDJSON output %q", lines)
+	}
+}
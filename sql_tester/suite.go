@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	_ "embed" // default suite
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultSuite is used when no -suite flag is given
+//
+//go:embed suites/sales.sql
+var defaultSuite string
+
+// loadSuite reads the queries from a suite file, or from every .sql file in a directory.
+// An empty path loads the built-in sales suite
+func loadSuite(path string) ([]Query, error) {
+	if path == "" {
+		return parseSuite("suites/sales.sql", strings.NewReader(defaultSuite))
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open suite: %w", err)
+	}
+	files := []string{path}
+	if info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*.sql"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list suite directory: %w", err)
+		}
+		sort.Strings(files)
+		if len(files) == 0 {
+			return nil, fmt.Errorf("suite directory %s contains no .sql files", path)
+		}
+	}
+
+	var queries []Query
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open suite: %w", err)
+		}
+		parsed, err := parseSuite(file, f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, parsed...)
+	}
+	return queries, nil
+}
+
+// parseSuite reads annotated SQL. Every test starts with "-- name:", may carry
+// "-- description:", "-- columns:", "-- expected:" (one per row), "-- ordered:",
+// "-- tolerance:", "-- setup:", "-- teardown:" and "-- expect-affected:" annotations,
+// and ends where the next test begins
+func parseSuite(filename string, r io.Reader) ([]Query, error) {
+	var queries []Query
+	var current *Query
+	var sqlLines []string
+	startLine := 0
+
+	// finish stores the test being read once its SQL is complete
+	finish := func() error {
+		if current == nil {
+			return nil
+		}
+		// Comments between the SQL and the next test belong to neither
+		for len(sqlLines) > 0 {
+			last := strings.TrimSpace(sqlLines[len(sqlLines)-1])
+			if last != "" && !strings.HasPrefix(last, "--") {
+				break
+			}
+			sqlLines = sqlLines[:len(sqlLines)-1]
+		}
+		current.SQL = strings.TrimSuffix(strings.TrimSpace(strings.Join(sqlLines, "\n")), ";")
+		if current.SQL == "" {
+			return fmt.Errorf("%s:%d: test %q has no SQL", filename, startLine, current.Name)
+		}
+		queries = append(queries, *current)
+		current, sqlLines = nil, nil
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		key, value, isAnnotation := parseAnnotation(line)
+
+		switch {
+		case isAnnotation && key == "name":
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			if value == "" {
+				return nil, fmt.Errorf("%s:%d: test name must not be empty", filename, lineNumber)
+			}
+			current = &Query{Name: value}
+			startLine = lineNumber
+		case current == nil:
+			// Comments and blank lines before the first test are ignored
+			if strings.TrimSpace(line) != "" && !strings.HasPrefix(strings.TrimSpace(line), "--") {
+				return nil, fmt.Errorf("%s:%d: SQL found before the first \"-- name:\" annotation", filename, lineNumber)
+			}
+		case isAnnotation && key == "description":
+			current.Description = value
+		case isAnnotation && key == "expected":
+			row, err := parseExpectedRow(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid expected row %q: %v", filename, lineNumber, value, err)
+			}
+			expectation(current).Rows = append(expectation(current).Rows, row)
+		case isAnnotation && (key == "setup" || key == "teardown"):
+			statements := splitStatements(value)
+			if len(statements) == 0 {
+				return nil, fmt.Errorf("%s:%d: %s must contain SQL", filename, lineNumber, key)
+			}
+			if key == "setup" {
+				current.Setup = append(current.Setup, statements...)
+			} else {
+				current.Teardown = append(current.Teardown, statements...)
+			}
+		case isAnnotation && key == "expect-affected":
+			affected, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || affected < 0 {
+				return nil, fmt.Errorf("%s:%d: expect-affected must be a non-negative integer, got %q", filename, lineNumber, value)
+			}
+			current.ExpectAffected = &affected
+		case isAnnotation && key == "columns":
+			expectation(current).Columns = strings.Split(value, ",")
+			for i, column := range expectation(current).Columns {
+				expectation(current).Columns[i] = strings.TrimSpace(column)
+			}
+		case isAnnotation && key == "ordered":
+			ordered, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: ordered must be true or false, got %q", filename, lineNumber, value)
+			}
+			expectation(current).Ordered = ordered
+		case isAnnotation && key == "tolerance":
+			tolerance, err := strconv.ParseFloat(value, 64)
+			if err != nil || tolerance < 0 {
+				return nil, fmt.Errorf("%s:%d: tolerance must be a non-negative number, got %q", filename, lineNumber, value)
+			}
+			expectation(current).Tolerance = tolerance
+		default:
+			// SQL, including comments that aren't annotations
+			sqlLines = append(sqlLines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read suite %s: %w", filename, err)
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("suite %s contains no tests", filename)
+	}
+	return queries, nil
+}
+
+// expectation returns the test's expectation, creating an empty one on first use
+func expectation(q *Query) *Expectation {
+	if q.Expected == nil {
+		q.Expected = &Expectation{Rows: [][]interface{}{}}
+	}
+	return q.Expected
+}
+
+// suiteAnnotations are the keys parseAnnotation recognizes
+var suiteAnnotations = map[string]bool{
+	"name":        true,
+	"description": true,
+	"expected":    true,
+	"columns":     true,
+	"ordered":     true,
+	"tolerance":   true,
+	"setup":       true,
+	"teardown":    true,
+	// expect-affected is the number of rows changed by the test's DML statements
+	"expect-affected": true,
+}
+
+// parseAnnotation recognizes "-- key: value" lines with a known key
+func parseAnnotation(line string) (key, value string, ok bool) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "--") {
+		return "", "", false
+	}
+	body := strings.TrimSpace(strings.TrimPrefix(trimmed, "--"))
+	colon := strings.IndexByte(body, ':')
+	if colon <= 0 {
+		return "", "", false
+	}
+	key = strings.ToLower(strings.TrimSpace(body[:colon]))
+	if !suiteAnnotations[key] {
+		return "", "", false
+	}
+	return key, strings.TrimSpace(body[colon+1:]), true
+}
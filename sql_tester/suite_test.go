@@ -0,0 +1,134 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSuite(t *testing.T) {
+	testCases := []struct {
+		name          string
+		input         string
+		expectedNames []string
+		expectedSQL   string
+		expectedError string
+	}{
+		{
+			name: "Annotated tests",
+			input: `-- Header comment: ignored
+-- name: First
+-- description: The first test
+-- expected: 1.5
+SELECT 1.5;
+
+-- name: Second
+SELECT 2
+FROM t;
+-- trailing comment`,
+			expectedNames: []string{"First", "Second"},
+			expectedSQL:   "SELECT 1.5",
+		},
+		{
+			name: "Comments inside SQL are kept",
+			input: `-- name: Commented
+SELECT a -- note: pick a
+FROM t`,
+			expectedNames: []string{"Commented"},
+			expectedSQL:   "SELECT a -- note: pick a\nFROM t",
+		},
+		{"SQL before first test", "SELECT 1;\n-- name: A\nSELECT 2", nil, "", "before the first"},
+		{"Missing SQL", "-- name: Empty\n-- expected: 1\n", nil, "", "has no SQL"},
+		{"Invalid tolerance", "-- name: A\n-- tolerance: lots\nSELECT 1", nil, "", "tolerance must be"},
+		{"Invalid ordered flag", "-- name: A\n-- ordered: maybe\nSELECT 1", nil, "", "ordered must be"},
+		{"No tests", "-- just a comment\n", nil, "", "contains no tests"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			queries, err := parseSuite("test.sql", strings.NewReader(tc.input))
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Fatalf("Expected error containing %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(queries) != len(tc.expectedNames) {
+				t.Fatalf("Expected %d queries, got %d", len(tc.expectedNames), len(queries))
+			}
+			for i, name := range tc.expectedNames {
+				if queries[i].Name != name {
+					t.Errorf("Expected name %q, got %q", name, queries[i].Name)
+				}
+			}
+			if queries[0].SQL != tc.expectedSQL {
+				t.Errorf("Expected SQL %q, got %q", tc.expectedSQL, queries[0].SQL)
+			}
+		})
+	}
+}
+
+func TestLoadSuite(t *testing.T) {
+	// The built-in suite holds the three sales tests
+	queries, err := loadSuite("")
+	if err != nil {
+		t.Fatalf("Failed to load built-in suite: %v", err)
+	}
+	if len(queries) != 4 || queries[1].Expected == nil || len(queries[1].Expected.Columns) != 2 {
+		t.Errorf("Unexpected built-in suite %+v", queries)
+	}
+
+	// A directory loads its .sql files in name order
+	tempDir, err := os.MkdirTemp("", "suite-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	os.WriteFile(filepath.Join(tempDir, "b.sql"), []byte("-- name: B\nSELECT 2"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "a.sql"), []byte("-- name: A\nSELECT 1"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "notes.txt"), []byte("not a suite"), 0644)
+
+	queries, err = loadSuite(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to load suite directory: %v", err)
+	}
+	if len(queries) != 2 || queries[0].Name != "A" || queries[1].Name != "B" {
+		t.Errorf("Expected tests A and B, got %+v", queries)
+	}
+
+	if _, err := loadSuite(filepath.Join(tempDir, "missing.sql")); err == nil {
+		t.Errorf("Expected error for missing suite file")
+	}
+}
+
+func TestParseSuiteExpectations(t *testing.T) {
+	input := `-- name: Totals
+-- columns: customer, total
+-- expected: 'Alice', 20000
+-- expected: Bob, NULL
+-- ordered: true
+-- tolerance: 0.5
+SELECT customer, total FROM totals`
+
+	queries, err := parseSuite("test.sql", strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := queries[0].Expected
+	if expected == nil {
+		t.Fatalf("Expected an expectation")
+	}
+	if len(expected.Columns) != 2 || expected.Columns[1] != "total" {
+		t.Errorf("Unexpected columns %v", expected.Columns)
+	}
+	if !expected.Ordered || expected.Tolerance != 0.5 {
+		t.Errorf("Expected ordered with tolerance 0.5, got %+v", expected)
+	}
+	if len(expected.Rows) != 2 || expected.Rows[0][0] != "Alice" || expected.Rows[0][1] != 20000.0 || expected.Rows[1][1] != nil {
+		t.Errorf("Unexpected rows %v", expected.Rows)
+	}
+}
@@ -0,0 +1,35 @@
+-- Default sql_tester suite for the sample orders table.
+--
+-- Each test starts with a "-- name:" line, followed by optional "-- description:",
+-- "-- columns:", "-- expected:" (one line per row), "-- ordered:", "-- tolerance:",
+-- "-- setup:", "-- teardown:" and "-- expect-affected:" lines and the SQL to run.
+-- Every test runs in a transaction that is rolled back afterwards.
+
+-- name: Total Sales for March 2024
+-- description: Calculate the total sales volume for March 2024
+-- expected: 27000
+SELECT SUM(amount) FROM orders WHERE order_date BETWEEN '2024-03-01' AND '2024-03-31';
+
+-- name: Top-spending Customer
+-- description: Find the customer who spent the most overall
+-- columns: customer, total_spent
+-- expected: Alice, 20000
+SELECT customer, SUM(amount) AS total_spent
+FROM orders
+GROUP BY customer
+ORDER BY total_spent DESC
+LIMIT 1;
+
+-- name: Average Order Value
+-- description: Calculate the average order value for all orders
+-- expected: 6000
+SELECT AVG(amount) FROM orders;
+
+-- name: Remove Bob's Orders
+-- description: Delete one customer's orders and check who is left
+-- expect-affected: 2
+-- columns: customer
+-- expected: Alice
+-- expected: Charlie
+DELETE FROM orders WHERE customer = 'Bob';
+SELECT DISTINCT customer FROM orders ORDER BY customer;
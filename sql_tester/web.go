@@ -1,40 +1,76 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
+	"net"
 	"net/http"
 	"strings"
+	"time"
 )
 
 // WebServer starts a web server that provides a GUI for SQL testing
-func runWebServer(db *sql.DB, port int) {
+func runWebServer(ctx context.Context, db *sql.DB, dialect string, port int, queries []Query, fixtures Fixtures, limits QueryLimits) error {
 	// Define a handler for the root path
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		serveHome(w, r, db)
+		serveHome(w, r, queries, fixtures, limits)
 	})
 
-	// Define a handler for executing queries
+	// Define handlers for executing queries and cancelling them by ID
+	running := newRunningQueries()
 	http.HandleFunc("/api/query", func(w http.ResponseWriter, r *http.Request) {
-		executeQuery(w, r, db)
+		executeQuery(w, r, db, dialect, limits, running)
+	})
+	http.HandleFunc("/api/query/cancel", func(w http.ResponseWriter, r *http.Request) {
+		cancelQuery(w, r, running)
+	})
+
+	// Define a handler for downloading query results as a file
+	http.HandleFunc("/api/export", func(w http.ResponseWriter, r *http.Request) {
+		exportQuery(w, r, db, dialect, limits, running)
+	})
+
+	// Define a handler for uploading data files into tables
+	http.HandleFunc("/api/import", func(w http.ResponseWriter, r *http.Request) {
+		importUpload(w, r, db, dialect, limits)
+	})
+
+	// Define a handler for running a suite test on the server
+	http.HandleFunc("/api/test", func(w http.ResponseWriter, r *http.Request) {
+		executeTest(w, r, db, queries, limits)
 	})
 
 	// Define a handler for getting table information
 	http.HandleFunc("/api/tables", func(w http.ResponseWriter, r *http.Request) {
-		getTableInfo(w, r, db)
+		getTableInfo(w, r, db, dialect)
 	})
 
-	// Start the server
+	// Start the server, and shut it down once ctx is done
 	addr := fmt.Sprintf(":%d", port)
 	fmt.Printf("Starting web server at http://localhost%s\n", addr)
-	log.Fatal(http.ListenAndServe(addr, nil))
+	// Requests inherit ctx, so running queries are cancelled when the server stops
+	server := &http.Server{Addr: addr, BaseContext: func(net.Listener) context.Context { return ctx }}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.ListenAndServe() }()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	fmt.Println("Shutting down web server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
 }
 
-// serveHome renders the home page
-func serveHome(w http.ResponseWriter, r *http.Request, db *sql.DB) {
+// serveHome renders the home page with the fixtures and the suite's queries
+func serveHome(w http.ResponseWriter, r *http.Request, queries []Query, fixtures Fixtures, limits QueryLimits) {
 	if r.URL.Path != "/" {
 		http.NotFound(w, r)
 		return
@@ -42,32 +78,13 @@ func serveHome(w http.ResponseWriter, r *http.Request, db *sql.DB) {
 
 	// Prepare template data
 	data := struct {
-		Queries []Query
+		Queries  []Query
+		Fixtures Fixtures
+		Limits   QueryLimits
 	}{
-		Queries: []Query{
-			{
-				Name:        "Total Sales for March 2024",
-				Description: "Calculate the total sales volume for March 2024",
-				SQL:         "SELECT SUM(amount) FROM orders WHERE order_date BETWEEN '2024-03-01' AND '2024-03-31'",
-				Expected:    27000.0,
-			},
-			{
-				Name:        "Top-spending Customer",
-				Description: "Find the customer who spent the most overall",
-				SQL: `SELECT customer, SUM(amount) AS total_spent 
-FROM orders 
-GROUP BY customer 
-ORDER BY total_spent DESC 
-LIMIT 1`,
-				Expected: 20000.0,
-			},
-			{
-				Name:        "Average Order Value",
-				Description: "Calculate the average order value for all orders",
-				SQL:         "SELECT AVG(amount) FROM orders",
-				Expected:    6000.0,
-			},
-		},
+		Queries:  queries,
+		Fixtures: fixtures,
+		Limits:   limits,
 	}
 
 	// Define the HTML template
@@ -185,6 +202,14 @@ LIMIT 1`,
         .schema-info {
             margin-top: 30px;
         }
+        table.expected {
+            width: auto;
+            margin-bottom: 10px;
+        }
+        .diff {
+            font-family: monospace;
+            color: #c0392b;
+        }
     </style>
 </head>
 <body>
@@ -192,34 +217,34 @@ LIMIT 1`,
     
     <div class="schema-info">
         <h2>Database Schema</h2>
-        <pre>
-CREATE TABLE orders (
-    id INTEGER PRIMARY KEY,
-    customer TEXT,
-    amount REAL,
-    order_date DATE
-);
-
+        {{if .Fixtures.Schema}}
+        <pre>{{.Fixtures.Schema}}
+{{if .Fixtures.Seed}}
 Sample data:
-INSERT INTO orders (customer, amount, order_date) VALUES
-('Alice', 5000, '2024-03-01'),
-('Bob', 8000, '2024-03-05'),
-('Alice', 3000, '2024-03-15'),
-('Charlie', 7000, '2024-02-20'),
-('Alice', 10000, '2024-02-28'),
-('Bob', 4000, '2024-02-10'),
-('Charlie', 9000, '2024-03-22'),
-('Alice', 2000, '2024-03-30');</pre>
+{{.Fixtures.Seed}}{{end}}</pre>
+        {{else}}
+        <p>The database is used as is, without fixtures. Its tables are listed by <a href="/api/tables">/api/tables</a>.</p>
+        {{end}}
     </div>
 
     <h2>Predefined Queries</h2>
-    {{range .Queries}}
+    {{range $index, $query := .Queries}}
     <div class="query-container">
-        <div class="query-title">{{.Name}}</div>
-        <div class="query-description">{{.Description}}</div>
-        <div class="query-sql">{{.SQL}}</div>
-        <div>Expected Result: {{printf "%.2f" .Expected}}</div>
-        <button onclick="runQuery(this, '{{.SQL}}', {{.Expected}})">Run Query</button>
+        <div class="query-title">{{$query.Name}}</div>
+        <div class="query-description">{{$query.Description}}</div>
+        <div class="query-sql">{{$query.SQL}}</div>
+        {{with $query.Expected}}
+        <div>Expected Result{{if .Ordered}} (ordered){{end}}{{if .Tolerance}} (tolerance {{.Tolerance}}){{end}}:</div>
+        <table class="expected">
+            {{if .Columns}}<thead><tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr></thead>{{end}}
+            <tbody>
+            {{range .Rows}}<tr>{{range .}}<td>{{formatValue .}}</td>{{end}}</tr>{{else}}<tr><td>(no rows)</td></tr>{{end}}
+            </tbody>
+        </table>
+        {{else}}
+        <div>No expected result; the test passes when the query runs.</div>
+        {{end}}
+        <button onclick="runTest(this, {{$index}})">Run Query</button>
         <div class="results"></div>
     </div>
     {{end}}
@@ -227,129 +252,268 @@ INSERT INTO orders (customer, amount, order_date) VALUES
     <div class="custom-query">
         <h2>Custom Query</h2>
         <p>Write your own SQL query below:</p>
+        <p class="query-description">
+            {{if .Limits.ReadOnly}}Queries run read-only and are rolled back.{{else}}Queries can change the database.{{end}}
+            {{if .Limits.Allowed}}Allowed statements: {{join .Limits.Allowed ", "}}.{{end}}
+            {{if .Limits.Timeout}}Timeout: {{.Limits.Timeout}}.{{end}}
+            {{if .Limits.MaxRows}}Results are paged, with at most {{.Limits.MaxRows}} rows per page.{{end}}
+        </p>
         <textarea id="custom-sql" placeholder="SELECT * FROM orders LIMIT 10;"></textarea>
-        <button onclick="runCustomQuery()">Run Custom Query</button>
+        <button id="run-query" onclick="runCustomQuery()">Run Custom Query</button>
+        <button id="cancel-query" onclick="cancelCustomQuery()" style="display: none;">Cancel</button>
+        <select id="export-format">
+            <option value="csv">CSV</option>
+            <option value="tsv">TSV</option>
+            <option value="jsonl">JSON lines</option>
+            <option value="xlsx">Excel (XLSX)</option>
+        </select>
+        <button onclick="exportCustomQuery()">Export</button>
+        <form id="export-form" method="POST" action="/api/export" target="export-frame" style="display: none;">
+            <input type="hidden" name="query">
+            <input type="hidden" name="format">
+        </form>
+        <iframe name="export-frame" id="export-frame" style="display: none;"></iframe>
         <div class="results" id="custom-results"></div>
     </div>
 
+    {{if .Limits.AllowImport}}
+    <div class="custom-query">
+        <h2>Import Data</h2>
+        <p>Load a CSV or JSON file into a new or existing table:</p>
+        <input type="file" id="import-file" accept=".csv,.tsv,.txt,.json,.jsonl,.ndjson">
+        <input type="text" id="import-table" placeholder="Table (default: file name)">
+        <input type="text" id="import-delimiter" placeholder="Delimiter" value="," size="3">
+        <label><input type="checkbox" id="import-header" checked> Header row</label>
+        <button onclick="importFile()">Import</button>
+        <div class="results" id="import-results"></div>
+    </div>
+    {{end}}
+
     <script>
-        function runQuery(button, sql, expected) {
+        function escapeHTML(value) {
+            return String(value)
+                .replace(/&/g, '&amp;')
+                .replace(/</g, '&lt;')
+                .replace(/>/g, '&gt;')
+                .replace(/"/g, '&quot;');
+        }
+
+        function formatCell(value) {
+            return value === null ? 'NULL' : escapeHTML(value);
+        }
+
+        function formatRow(row) {
+            return (row || []).map(formatCell).join(' | ');
+        }
+
+        function runTest(button, index) {
             const resultsDiv = button.nextElementSibling;
             resultsDiv.style.display = 'block';
             resultsDiv.innerHTML = 'Loading...';
-            
-            fetch('/api/query', {
+
+            fetch('/api/test', {
                 method: 'POST',
                 headers: {
                     'Content-Type': 'application/json',
                 },
-                body: JSON.stringify({ query: sql }),
+                body: JSON.stringify({ index: index }),
             })
             .then(response => response.json())
             .then(data => {
                 if (data.error) {
-                    resultsDiv.innerHTML = '<div class="error">Error: ' + data.error + '</div>';
+                    resultsDiv.innerHTML = '<div class="error">Error: ' + escapeHTML(data.error) + '</div>';
                     return;
                 }
-                
+
                 let html = '<h3>Results:</h3>';
-                
-                // Create table for results
-                if (data.results && data.results.length > 0) {
+                const actual = data.actual;
+                if (actual.rows.length > 0) {
                     html += '<table><thead><tr>';
-                    
-                    // Table headers from the first result's keys
-                    for (const key in data.results[0]) {
-                        html += '<th>' + key + '</th>';
+                    for (const column of actual.columns) {
+                        html += '<th>' + escapeHTML(column) + '</th>';
                     }
                     html += '</tr></thead><tbody>';
-                    
-                    // Table rows
-                    for (const row of data.results) {
+                    for (const row of actual.rows) {
                         html += '<tr>';
-                        for (const key in row) {
-                            html += '<td>' + row[key] + '</td>';
+                        for (const value of row) {
+                            html += '<td>' + formatCell(value) + '</td>';
                         }
                         html += '</tr>';
                     }
                     html += '</tbody></table>';
-                    
-                    // Check if result matches expected value
-                    let actualValue = null;
-                    if (data.results[0].hasOwnProperty('SUM(amount)')) {
-                        actualValue = data.results[0]['SUM(amount)'];
-                    } else if (data.results[0].hasOwnProperty('AVG(amount)')) {
-                        actualValue = data.results[0]['AVG(amount)'];
-                    } else if (data.results[0].hasOwnProperty('total_spent')) {
-                        actualValue = data.results[0]['total_spent'];
-                    }
-                    
-                    if (actualValue !== null && Math.abs(actualValue - expected) < 0.01) {
-                        html += '<div class="success">✓ Result matches expected value!</div>';
-                    } else if (actualValue !== null) {
-                        html += '<div class="error">✗ Result does not match expected value. Expected: ' + expected.toFixed(2) + '</div>';
-                    }
                 } else {
                     html += '<p>No results returned</p>';
                 }
-                
+                if (actual.columns.length === 0 || data.affected > 0) {
+                    html += '<p>Rows affected: ' + data.affected + ' (rolled back)</p>';
+                }
+
+                // The comparison happens on the server; only render its verdict and diff
+                if (data.passed) {
+                    html += '<div class="success">✓ Result matches expected value!</div>';
+                } else {
+                    html += '<div class="error">✗ Result does not match expected value:</div><ul class="diff">';
+                    for (const diff of data.diff) {
+                        if (diff.kind === 'affected') {
+                            html += '<li>~ rows affected: expected ' + formatRow(diff.expected) + ', got ' + formatRow(diff.actual) + '</li>';
+                        } else if (diff.kind === 'columns') {
+                            html += '<li>~ columns: expected ' + formatRow(diff.expected) + ', got ' + formatRow(diff.actual) + '</li>';
+                        } else if (diff.kind === 'missing') {
+                            html += '<li>- missing' + (diff.row ? ' row ' + diff.row : '') + ': ' + formatRow(diff.expected) + '</li>';
+                        } else if (diff.kind === 'unexpected') {
+                            html += '<li>+ unexpected row ' + diff.row + ': ' + formatRow(diff.actual) + '</li>';
+                        } else {
+                            html += '<li>~ row ' + diff.row + ': expected ' + formatRow(diff.expected) + ', got ' + formatRow(diff.actual) + '</li>';
+                        }
+                    }
+                    html += '</ul>';
+                }
+
                 resultsDiv.innerHTML = html;
             })
             .catch(error => {
-                resultsDiv.innerHTML = '<div class="error">Error: ' + error.message + '</div>';
+                resultsDiv.innerHTML = '<div class="error">Error: ' + escapeHTML(error.message) + '</div>';
+            });
+        }
+
+        // runningQueryID identifies the custom query in progress, so that it can be cancelled
+        let runningQueryID = null;
+
+        function setQueryRunning(id) {
+            runningQueryID = id;
+            document.getElementById('run-query').disabled = id !== null;
+            document.getElementById('cancel-query').style.display = id !== null ? 'inline-block' : 'none';
+        }
+
+        function cancelCustomQuery() {
+            if (runningQueryID === null) {
+                return;
+            }
+            fetch('/api/query/cancel', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({ id: runningQueryID }),
+            });
+        }
+
+        // pageSize is the number of rows fetched per page of custom query results
+        const pageSize = 100;
+        // customQuery remembers the query being paged through
+        let customQuery = null;
+
+        function exportCustomQuery() {
+            const form = document.getElementById('export-form');
+            const frame = document.getElementById('export-frame');
+            form.elements.query.value = document.getElementById('custom-sql').value;
+            form.elements.format.value = document.getElementById('export-format').value;
+            // The download streams straight to a file from the hidden frame; the frame only
+            // loads a page when the server answers with an error instead
+            frame.onload = () => {
+                let message = 'Export failed';
+                try {
+                    message = JSON.parse(frame.contentDocument.body.textContent).error || message;
+                } catch (error) {}
+                const resultsDiv = document.getElementById('custom-results');
+                resultsDiv.style.display = 'block';
+                resultsDiv.innerHTML = '<div class="error">Error: ' + escapeHTML(message) + '</div>';
+            };
+            form.submit();
+        }
+
+        function importFile() {
+            const resultsDiv = document.getElementById('import-results');
+            const file = document.getElementById('import-file').files[0];
+            resultsDiv.style.display = 'block';
+            if (!file) {
+                resultsDiv.innerHTML = '<div class="error">Choose a file to import</div>';
+                return;
+            }
+            const form = new FormData();
+            form.append('file', file);
+            form.append('table', document.getElementById('import-table').value);
+            form.append('delimiter', document.getElementById('import-delimiter').value);
+            form.append('header', document.getElementById('import-header').checked);
+            resultsDiv.innerHTML = 'Importing...';
+
+            fetch('/api/import', { method: 'POST', body: form })
+            .then(response => response.json())
+            .then(data => {
+                if (data.error) {
+                    resultsDiv.innerHTML = '<div class="error">Error: ' + escapeHTML(data.error) + '</div>';
+                    return;
+                }
+                const columns = data.columns.map((column, i) => escapeHTML(column) + ' ' + data.types[i]).join(', ');
+                resultsDiv.innerHTML = '<div class="success">' + (data.created ? 'Created table ' : 'Imported into ') +
+                    escapeHTML(data.table) + ': ' + data.rows + ' row(s)</div><div>' + columns + '</div>';
+            })
+            .catch(error => {
+                resultsDiv.innerHTML = '<div class="error">Error: ' + escapeHTML(error.message) + '</div>';
             });
         }
-        
+
         function runCustomQuery() {
-            const sql = document.getElementById('custom-sql').value;
+            customQuery = document.getElementById('custom-sql').value;
+            loadCustomPage({ offset: 0 });
+        }
+
+        function loadCustomPage(page) {
             const resultsDiv = document.getElementById('custom-results');
             resultsDiv.style.display = 'block';
             resultsDiv.innerHTML = 'Loading...';
+            setQueryRunning(Date.now().toString(36) + Math.random().toString(36).slice(2));
             
             fetch('/api/query', {
                 method: 'POST',
                 headers: {
                     'Content-Type': 'application/json',
                 },
-                body: JSON.stringify({ query: sql }),
+                body: JSON.stringify(Object.assign({ query: customQuery, id: runningQueryID, limit: pageSize }, page)),
             })
             .then(response => response.json())
             .then(data => {
                 if (data.error) {
-                    resultsDiv.innerHTML = '<div class="error">Error: ' + data.error + '</div>';
+                    resultsDiv.innerHTML = '<div class="error">Error: ' + escapeHTML(data.error) + '</div>';
                     return;
                 }
                 
                 let html = '<h3>Results:</h3>';
                 
-                // Create table for results
-                if (data.results && data.results.length > 0) {
+                // Create table for results, keeping the columns in query order
+                if (data.rows.length > 0) {
                     html += '<table><thead><tr>';
-                    
-                    // Table headers from the first result's keys
-                    for (const key in data.results[0]) {
-                        html += '<th>' + key + '</th>';
+                    for (const column of data.columns) {
+                        html += '<th>' + escapeHTML(column) + '</th>';
                     }
                     html += '</tr></thead><tbody>';
-                    
-                    // Table rows
-                    for (const row of data.results) {
+                    for (const row of data.rows) {
                         html += '<tr>';
-                        for (const key in row) {
-                            html += '<td>' + row[key] + '</td>';
+                        for (const value of row) {
+                            html += '<td>' + formatCell(value) + '</td>';
                         }
                         html += '</tr>';
                     }
                     html += '</tbody></table>';
+                    html += '<p>Rows ' + (data.offset + 1) + '-' + (data.offset + data.count) + (data.truncated ? ' (more available)' : '') + '</p>';
                 } else {
                     html += '<p>No results returned</p>';
                 }
+
+                // Page through the results on the server
+                if (data.offset > 0) {
+                    html += '<button onclick="loadCustomPage({ offset: ' + Math.max(0, data.offset - pageSize) + ' })">Previous page</button> ';
+                }
+                if (data.next_cursor) {
+                    html += '<button onclick="loadCustomPage({ cursor: \'' + data.next_cursor + '\' })">Next page</button>';
+                }
                 
                 resultsDiv.innerHTML = html;
             })
             .catch(error => {
-                resultsDiv.innerHTML = '<div class="error">Error: ' + error.message + '</div>';
-            });
+                resultsDiv.innerHTML = '<div class="error">Error: ' + escapeHTML(error.message) + '</div>';
+            })
+            .finally(() => setQueryRunning(null));
         }
     </script>
 </body>
@@ -357,7 +521,7 @@ INSERT INTO orders (customer, amount, order_date) VALUES
 `
 
 	// Parse and execute the template
-	t, err := template.New("home").Parse(tmpl)
+	t, err := template.New("home").Funcs(template.FuncMap{"formatValue": formatValue, "join": strings.Join}).Parse(tmpl)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error parsing template: %v", err), http.StatusInternalServerError)
 		return
@@ -369,8 +533,10 @@ INSERT INTO orders (customer, amount, order_date) VALUES
 	}
 }
 
-// executeQuery executes a SQL query and returns the results as JSON
-func executeQuery(w http.ResponseWriter, r *http.Request, db *sql.DB) {
+// executeQuery executes a custom SQL query within the limits and streams the page of
+// results as JSON or NDJSON. The query runs with the request's context, so it stops when
+// the client goes away, and can be cancelled through /api/query/cancel with its id
+func executeQuery(w http.ResponseWriter, r *http.Request, db *sql.DB, dialect string, limits QueryLimits, running *runningQueries) {
 	// Only accept POST requests
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -380,153 +546,132 @@ func executeQuery(w http.ResponseWriter, r *http.Request, db *sql.DB) {
 	// Parse the request body
 	var request struct {
 		Query string `json:"query"`
+		ID    string `json:"id"`
+		// Format is "json" (default) or "ndjson"; an Accept header of application/x-ndjson also selects NDJSON
+		Format string `json:"format"`
+		Offset int    `json:"offset"`
+		Limit  int    `json:"limit"`
+		// Cursor is the next_cursor of a previous page, and takes precedence over Offset
+		Cursor string `json:"cursor"`
 	}
 	err := json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error parsing request: %v", err), http.StatusBadRequest)
 		return
 	}
+	if request.Format == "" && strings.Contains(r.Header.Get("Accept"), "application/x-ndjson") {
+		request.Format = "ndjson"
+	}
+	if request.Format != "" && request.Format != "json" && request.Format != "ndjson" {
+		writeQueryError(w, http.StatusBadRequest, "", fmt.Errorf("unknown format %q (supported: json, ndjson)", request.Format))
+		return
+	}
 
-	// Execute the query
-	rows, err := db.Query(request.Query)
-	if err != nil {
-		// Return error as JSON
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": err.Error(),
-		})
+	page := Page{Offset: request.Offset, Limit: request.Limit}
+	if request.Cursor != "" {
+		if page.Offset, err = decodeCursor(request.Cursor, request.Query); err != nil {
+			writeQueryError(w, http.StatusBadRequest, "", err)
+			return
+		}
+	}
+	if page.Offset < 0 || page.Limit < 0 {
+		writeQueryError(w, http.StatusBadRequest, "", fmt.Errorf("offset and limit can't be negative"))
 		return
 	}
-	defer rows.Close()
 
-	// Get column names
-	columns, err := rows.Columns()
+	// Register the query so it can be cancelled
+	id, ctx, done, err := running.start(r.Context(), request.ID)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": fmt.Sprintf("Error getting columns: %v", err),
-		})
+		writeQueryError(w, http.StatusConflict, "", err)
 		return
 	}
-
-	// Prepare a slice to hold the results
-	var results []map[string]interface{}
-
-	// Prepare a slice to hold values for each row
-	values := make([]interface{}, len(columns))
-	valuePtrs := make([]interface{}, len(columns))
-	for i := range columns {
-		valuePtrs[i] = &values[i]
+	defer done()
+
+	// Execute the query, writing rows as they are read
+	stream := newResultStream(w, request.Format, id)
+	truncated, err := runSandboxedQuery(ctx, db, dialect, request.Query, limits, page, stream.begin, stream.row)
+	if err != nil && !stream.started {
+		// Nothing was written yet; statements the sandbox refuses are forbidden
+		status := http.StatusOK
+		if errors.Is(err, errQueryRejected) {
+			status = http.StatusForbidden
+		}
+		writeQueryError(w, status, id, err)
+		return
 	}
 
-	// Iterate through rows
-	for rows.Next() {
-		// Scan the row into the slice of pointers
-		err := rows.Scan(valuePtrs...)
-		if err != nil {
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": fmt.Sprintf("Error scanning row: %v", err),
-			})
-			return
-		}
+	summary := pageSummary{Offset: page.Offset, Truncated: truncated}
+	if truncated && err == nil {
+		summary.Cursor = encodeCursor(request.Query, page.Offset+stream.count)
+	}
+	if err := stream.end(summary, err); err != nil {
+		log.Printf("Error writing query results: %v", err)
+	}
+}
 
-		// Create a map for this row
-		row := make(map[string]interface{})
-		for i, col := range columns {
-			// Convert to appropriate type and store in the map
-			var v interface{}
-			val := values[i]
-			b, ok := val.([]byte)
-			if ok {
-				v = string(b)
-			} else {
-				v = val
-			}
-			row[col] = v
-		}
+// writeQueryError returns a query error as JSON
+func writeQueryError(w http.ResponseWriter, status int, id string, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	response := map[string]string{"error": err.Error()}
+	if id != "" {
+		response["id"] = id
+	}
+	json.NewEncoder(w).Encode(response)
+}
 
-		// Add the row to the results
-		results = append(results, row)
+// executeTest runs one of the suite's tests and returns its result and row diff as JSON
+func executeTest(w http.ResponseWriter, r *http.Request, db *sql.DB, queries []Query, limits QueryLimits) {
+	// Only accept POST requests
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
-	// Check for errors in rows.Next()
-	if err := rows.Err(); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": fmt.Sprintf("Error iterating rows: %v", err),
-		})
+	// Parse the request body
+	var request struct {
+		Index int `json:"index"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("Error parsing request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if request.Index < 0 || request.Index >= len(queries) {
+		http.Error(w, fmt.Sprintf("Unknown test %d", request.Index), http.StatusNotFound)
 		return
 	}
 
-	// Return the results as JSON
+	// Tests get the same time limit as custom queries
+	ctx := r.Context()
+	if limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.Timeout)
+		defer cancel()
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"results": results,
-	})
+	json.NewEncoder(w).Encode(runTest(ctx, db, queries[request.Index]))
 }
 
 // getTableInfo returns information about the tables in the database
-func getTableInfo(w http.ResponseWriter, r *http.Request, db *sql.DB) {
-	// Query to get table names
-	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type='table'")
+func getTableInfo(w http.ResponseWriter, r *http.Request, db *sql.DB, dialect string) {
+	// Get the table names from the dialect's catalog
+	tables, err := listTables(db, dialect)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{
-			"error": fmt.Sprintf("Error getting tables: %v", err),
+			"error": err.Error(),
 		})
 		return
 	}
-	defer rows.Close()
-
-	// Collect table names
-	var tables []string
-	for rows.Next() {
-		var name string
-		if err := rows.Scan(&name); err != nil {
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": fmt.Sprintf("Error scanning table name: %v", err),
-			})
-			return
-		}
-		tables = append(tables, name)
-	}
 
 	// Get schema for each table
 	var tableInfo []map[string]interface{}
 	for _, tableName := range tables {
-		// Skip internal SQLite tables
-		if strings.HasPrefix(tableName, "sqlite_") {
-			continue
-		}
-
-		// Get table schema
-		schemaRows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", tableName))
+		columns, err := describeColumns(db, dialect, tableName)
 		if err != nil {
 			continue
 		}
-		defer schemaRows.Close()
-
-		var columns []map[string]interface{}
-		for schemaRows.Next() {
-			var cid int
-			var name, typeName string
-			var notNull, pk int
-			var dfltValue interface{}
-
-			if err := schemaRows.Scan(&cid, &name, &typeName, &notNull, &dfltValue, &pk); err != nil {
-				continue
-			}
-
-			columns = append(columns, map[string]interface{}{
-				"name":     name,
-				"type":     typeName,
-				"not_null": notNull == 1,
-				"pk":       pk == 1,
-				"default":  dfltValue,
-			})
-		}
 
 		tableInfo = append(tableInfo, map[string]interface{}{
 			"name":    tableName,